@@ -12,11 +12,13 @@ import (
 	"proxy-checker/internal/proxy"
 	"strings"
 	"syscall"
+	"time"
 )
 
 type BotCommand struct {
-	fs  *flag.FlagSet
-	cfg *config.Config
+	fs      *flag.FlagSet
+	cfg     *config.Config
+	checker proxy.Checker
 
 	verbose     bool
 	concurrency uint
@@ -56,7 +58,13 @@ func (g *BotCommand) Init(args []string) error {
 	}
 
 	setupLogger(g.cfg)
-	checkInternetConnection()
+	if err := checkJudgeConnection(g.cfg.API); err != nil {
+		return err
+	}
+
+	// One checker serves all messages, so that its rate limit and host and subnet caps hold
+	// across messages checked at the same time.
+	g.checker = proxy.NewChecker(g.cfg.ProxyChecker)
 
 	slog.Info("starting bot...")
 	slog.Debug("debug enabled")
@@ -90,32 +98,56 @@ func (g *BotCommand) Run(ctx context.Context) error {
 			continue
 		}
 
+		// The text holds proxies with their credentials, only its size is logged.
 		slog.With(
 			slog.String("username", update.Message.From.UserName),
-			slog.String("msg", update.Message.Text),
+			slog.Int("lines", strings.Count(strings.TrimSpace(update.Message.Text), "\n")+1),
 		).Info("Received message")
 
-		go handleUpdate(ctx, bot, g.cfg, update)
+		go handleUpdate(ctx, bot, g.checker, g.cfg, update)
 	}
 
 	return err
 }
 
-func handleUpdate(ctx context.Context, bot *tgbotapi.BotAPI, cfg *config.Config, update tgbotapi.Update) {
-	proxiesCh := make(chan string, cfg.Concurrency)
+func handleUpdate(ctx context.Context, bot *tgbotapi.BotAPI, checker proxy.Checker, cfg *config.Config, update tgbotapi.Update) {
+	proxiesCh := make(chan proxy.Spec, cfg.Concurrency)
 
 	go func() {
 		req := strings.Split(update.Message.Text, "\n")
 
 		for _, p := range req {
-			proxiesCh <- p
+			spec, _ := proxy.ParseSpec(strings.TrimSpace(p))
+			proxiesCh <- spec
 		}
 		close(proxiesCh)
 	}()
 
-	resp, _ := proxy.NewChecker(cfg.ProxyChecker).AwaitCheck(ctx, proxiesCh)
+	dedup := proxy.NewDedup()
+	results, _ := checker.AwaitCheck(ctx, dedup.Filter(proxiesCh))
+
+	summary := proxy.Summarize(results)
+	summary.Duplicates = dedup.Dropped()
 
-	if _, err := bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, strings.Join(resp, "\n"))); err != nil {
+	if _, err := bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, formatResults(results, summary))); err != nil {
 		slog.Error("sending message failed", slog.String("error", err.Error()))
 	}
 }
+
+func formatResults(results []proxy.Result, summary proxy.Summary) string {
+	lines := make([]string, 0, len(results))
+
+	for _, res := range results {
+		if !res.OK() {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%s %s %s", res.Proxy, strings.Join(res.Protocols, ","), res.BestLatency().Round(time.Millisecond)))
+	}
+
+	if len(lines) == 0 {
+		lines = append(lines, "no working proxies found")
+	}
+
+	return strings.Join(append(lines, "", summary.String()), "\n")
+}
@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"os"
+	"proxy-checker/internal/proxy"
 	"testing"
+	"time"
 )
 
 func TestBotCommand_Init(t *testing.T) {
+	startJudge(t)
 	botCmd := NewBotCommand()
 
 	os.Setenv("TELEGRAM_API_TOKEN", "tg-token")
@@ -22,6 +25,10 @@ func TestBotCommand_Init(t *testing.T) {
 	if botCmd.concurrency != 10 {
 		t.Errorf("expected concurrency to be 10, got %d", botCmd.concurrency)
 	}
+
+	if botCmd.checker == nil {
+		t.Errorf("expected a checker shared by all messages")
+	}
 }
 
 func TestBotCommand_Name(t *testing.T) {
@@ -31,3 +38,20 @@ func TestBotCommand_Name(t *testing.T) {
 		t.Errorf("expected command name to be %s, got %s", expectedName, botCmd.Name())
 	}
 }
+
+func TestFormatResults(t *testing.T) {
+	results := []proxy.Result{
+		{Proxy: proxy.Spec{Host: "127.0.0.1", Port: "8080"}, Protocols: []string{"http", "socks5"}, Latency: map[string]proxy.Timing{"http": {Total: 300 * time.Millisecond}, "socks5": {Total: 120 * time.Millisecond}}},
+		{Proxy: proxy.Spec{Host: "127.0.0.1", Port: "8081"}, Error: "request failed", Category: proxy.CategoryTimeout},
+	}
+
+	expected := "127.0.0.1:8080 http,socks5 120ms\n\nchecked 2, alive 1, timeout 1"
+	if msg := formatResults(results, proxy.Summarize(results)); msg != expected {
+		t.Errorf("expected message %q, got %q", expected, msg)
+	}
+
+	expected = "no working proxies found\n\nchecked 1, alive 0, timeout 1"
+	if msg := formatResults(results[1:], proxy.Summarize(results[1:])); msg != expected {
+		t.Errorf("expected message %q, got %q", expected, msg)
+	}
+}
@@ -2,25 +2,41 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"golang.org/x/sync/errgroup"
 	"log/slog"
 	"os"
 	"os/signal"
 	"proxy-checker/internal/config"
 	"proxy-checker/internal/proxy"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 )
 
 type CliCommand struct {
-	fs  *flag.FlagSet
-	cfg *config.Config
+	fs      *flag.FlagSet
+	cfg     *config.Config
+	checker proxy.Checker
 
 	output      string
-	input       string
+	failed      string
+	checkpoint  string
+	resume      bool
+	format      proxy.Format
+	columns     []string
+	inputs      stringList
+	inputFormat proxy.Format
 	verbose     bool
+	quiet       bool
 	concurrency uint
+	anonymity   proxy.Anonymity
+	maxLatency  time.Duration
+	sortBy      string
+	minAlive    uint
 }
 
 func NewCliCommand() *CliCommand {
@@ -28,10 +44,44 @@ func NewCliCommand() *CliCommand {
 		fs: flag.NewFlagSet("cli", flag.ContinueOnError),
 	}
 
-	gc.fs.StringVar(&gc.output, "o", "stdout", "output file")
-	gc.fs.StringVar(&gc.input, "i", "stdin", "input file")
+	gc.fs.StringVar(&gc.output, "o", "stdout", "output file, placeholders such as out/{protocol}.txt split the output")
+	gc.fs.StringVar(&gc.failed, "failed", "", "report failed proxies with their error category and error to this file, text or jsonl")
+	gc.fs.StringVar(&gc.checkpoint, "checkpoint", "", "file recording the checked proxies of the run, removed once it completes (default <output>.checkpoint)")
+	gc.fs.BoolVar(&gc.resume, "resume", false, "resume an interrupted run from its checkpoint, appending to its output")
+	gc.fs.Func("format", "output format: text, jsonl, json, csv, proxychains, pac, urls or template:path, guessed from the file extension by default", func(s string) (err error) {
+		gc.format, err = proxy.ParseFormat(s)
+		return err
+	})
+	gc.fs.Func("columns", "comma-separated csv columns (default "+strings.Join(proxy.DefaultColumns, ",")+")", func(s string) error {
+		for _, col := range strings.Split(s, ",") {
+			if col = strings.TrimSpace(col); col != "" {
+				gc.columns = append(gc.columns, col)
+			}
+		}
+		return nil
+	})
+	gc.fs.Var(&gc.inputs, "i", "input file, directory, glob or http(s) URL, may be repeated (default stdin)")
+	gc.fs.Func("input-format", "input format: text, csv, json or jsonl, guessed from the file extension by default", func(s string) (err error) {
+		gc.inputFormat, err = proxy.ParseInputFormat(s)
+		return err
+	})
 	gc.fs.UintVar(&gc.concurrency, "c", 0, "concurrency limit")
 	gc.fs.BoolVar(&gc.verbose, "v", false, "verbosity mode")
+	gc.fs.BoolVar(&gc.quiet, "quiet", false, "no progress, summary or info logs, for scripts")
+	gc.fs.Func("anonymity", "minimal anonymity level: transparent, anonymous or elite", func(s string) (err error) {
+		gc.anonymity, err = proxy.ParseAnonymity(s)
+		return err
+	})
+	gc.fs.UintVar(&gc.minAlive, "min-alive", 0, "exit with code 5 when fewer working proxies are written")
+	gc.fs.DurationVar(&gc.maxLatency, "max-latency", 0, "skip proxies slower than this, e.g. 800ms")
+	gc.fs.Func("sort", "sort output by latency or throughput, buffers all results", func(s string) error {
+		if s != "latency" && s != "throughput" {
+			return fmt.Errorf("unknown sort key: %s", s)
+		}
+
+		gc.sortBy = s
+		return nil
+	})
 
 	return gc
 }
@@ -42,7 +92,7 @@ func (g *CliCommand) Name() string {
 
 func (g *CliCommand) Init(args []string) error {
 	if err := g.fs.Parse(args); err != nil {
-		return err
+		return withExitCode(ExitInput, err)
 	}
 
 	if err := setConcurrencyEnv(g.concurrency); err != nil {
@@ -53,18 +103,88 @@ func (g *CliCommand) Init(args []string) error {
 		return err
 	}
 
+	if err := setQuietMode(g.quiet); err != nil {
+		return err
+	}
+
+	if err := setAnonymityEnv(g.anonymity); err != nil {
+		return err
+	}
+
+	if len(g.inputs) == 0 {
+		g.inputs = stringList{"stdin"}
+	}
+
+	if g.checkpoint == "" && g.output != "stdout" && !strings.Contains(g.output, "{") {
+		g.checkpoint = g.output + ".checkpoint"
+	}
+
+	if g.resume && g.checkpoint == "" {
+		return withExitCode(ExitInput, errors.New("-resume needs a -checkpoint file when writing to stdout"))
+	}
+
 	g.cfg = config.MustLoad()
 
 	setupLogger(g.cfg)
-	checkInternetConnection()
+	if err := checkJudgeConnection(g.cfg.API); err != nil {
+		return err
+	}
+
+	// Our own addresses are looked up once for the whole run, without them no proxy could
+	// be checked.
+	g.checker = proxy.NewChecker(g.cfg.ProxyChecker)
+	if err := g.checker.CheckJudge(); errors.Is(err, proxy.ErrJudgeHeaders) {
+		return withExitCode(ExitInput, fmt.Errorf("-anonymity: %w", err))
+	} else if err != nil {
+		return withExitCode(ExitJudgeUnreachable, err)
+	}
 
-	slog.Info("starting", slog.String("in", g.input), slog.String("out", g.output))
+	slog.Info("starting", slog.String("in", g.inputs.String()), slog.String("out", g.output))
 	slog.Debug("debug enabled")
 
 	return nil
 }
 
+// keep reports whether a working proxy passes the anonymity and latency filters.
+func (g *CliCommand) keep(res proxy.Result) bool {
+	return res.Anonymity.AtLeast(g.anonymity) && (g.maxLatency == 0 || res.BestLatency() <= g.maxLatency)
+}
+
+// checkAlive fails a run that wrote no working proxies or fewer than -min-alive.
+func (g *CliCommand) checkAlive(written uint) error {
+	switch {
+	case written == 0:
+		return withExitCode(ExitNoneAlive, errors.New("no proxies alive"))
+	case written < g.minAlive:
+		return withExitCode(ExitTooFewAlive, fmt.Errorf("%d proxies alive, fewer than -min-alive %d", written, g.minAlive))
+	default:
+		return nil
+	}
+}
+
+// showProgress reports whether the progress line is drawn, it is left out of the way of
+// scripts and of proxies typed in on the terminal.
+func (g *CliCommand) showProgress() bool {
+	if g.cfg.Quiet || !isTerminal(os.Stderr) {
+		return false
+	}
+
+	return !slices.Contains(g.inputs, "stdin") || !isTerminal(os.Stdin)
+}
+
 func (g *CliCommand) Run(ctx context.Context) error {
+	reader, err := proxy.NewInputReader(g.inputs, g.inputFormat)
+	if err != nil {
+		return withExitCode(ExitInput, err)
+	}
+
+	var checkpoint *proxy.Checkpoint
+	if g.checkpoint != "" {
+		if checkpoint, err = proxy.OpenCheckpoint(g.checkpoint, g.resume); err != nil {
+			return err
+		}
+	}
+
 	exit := make(chan error)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -76,19 +196,106 @@ func (g *CliCommand) Run(ctx context.Context) error {
 		cancel()
 
 		<-time.After(g.cfg.ShutdownTimeout)
+		// The output may miss results that are not flushed yet, they are in the checkpoint
+		// and written again by a resumed run.
+		slog.Warn("shutdown timed out", slog.String("checkpoint", g.checkpoint))
 		exit <- nil
 	}()
 
+	runCtx := ctx
 	eg, ctx := errgroup.WithContext(ctx)
 
-	proxiesCh := make(chan string)
+	proxiesCh := make(chan proxy.Spec)
 	eg.Go(func() error {
-		return proxy.NewReader(g.input).Read(ctx, proxiesCh)
+		return withExitCode(ExitInput, reader.Read(ctx, proxiesCh))
+	})
+
+	var (
+		summary proxy.Summary
+		written uint
+	)
+	dedup := proxy.NewDedup()
+	specsCh := dedup.Filter(proxiesCh)
+
+	if checkpoint != nil {
+		if checkpoint.Resumed() {
+			slog.Info("resuming", slog.String("checkpoint", g.checkpoint), slog.Int("checked", len(checkpoint.Results())))
+		}
+
+		for _, res := range checkpoint.Results() {
+			summary.Add(res)
+		}
+
+		specsCh = checkpoint.Skip(specsCh)
+	}
+
+	var bar *progress
+	if g.showProgress() {
+		bar = newProgress(os.Stderr)
+		specsCh = countSpecs(specsCh, bar.Queue, bar.InputDone)
+	}
+
+	resultCh, errorsCh := g.checker.Check(ctx, specsCh)
+	resultCh = filterResults(resultCh, func(res proxy.Result) bool {
+		// Checks cut short by an interruption say nothing about the proxy, a resumed run
+		// checks it again.
+		return res.OK() || ctx.Err() == nil
+	})
+	resultCh = tapResults(resultCh, func(res proxy.Result) {
+		summary.Add(res)
+
+		if checkpoint != nil {
+			if err := checkpoint.Record(res); err != nil {
+				slog.Warn("failed to record checkpoint", slog.String("error", err.Error()))
+			}
+		}
+
+		if bar != nil {
+			bar.Add(res)
+		}
 	})
 
-	resultCh, errorsCh := proxy.NewChecker(g.cfg.ProxyChecker).Check(ctx, proxiesCh)
+	if bar != nil {
+		bar.Start()
+	}
+
+	if checkpoint != nil {
+		// The output is written anew with the results of the earlier run, whatever it
+		// had not flushed when it was stopped is not lost.
+		resultCh = prependResults(checkpoint.Results(), resultCh)
+	}
+
+	if g.failed != "" {
+		var failedCh <-chan proxy.Result
+		resultCh, failedCh = partitionResults(resultCh, proxy.Result.OK)
+
+		eg.Go(func() error {
+			return proxy.NewWriter(g.failed, proxy.WriteOptions{Failed: true}).Write(ctx, failedCh)
+		})
+	}
+
+	resultCh = filterResults(resultCh, func(res proxy.Result) bool {
+		return !res.OK() || g.keep(res)
+	})
+	resultCh = tapResults(resultCh, func(res proxy.Result) {
+		if res.OK() {
+			written++
+		}
+	})
+
+	switch g.sortBy {
+	case "latency":
+		resultCh = sortResults(resultCh, func(a, b proxy.Result) bool {
+			return a.BestLatency() < b.BestLatency()
+		})
+	case "throughput":
+		resultCh = sortResults(resultCh, func(a, b proxy.Result) bool {
+			return a.Throughput > b.Throughput
+		})
+	}
+
 	eg.Go(func() error {
-		return proxy.NewWriter(g.output).Write(ctx, resultCh)
+		return proxy.NewWriter(g.output, proxy.WriteOptions{Format: g.format, Columns: g.columns}).Write(ctx, resultCh)
 	})
 
 	eg.Go(func() error {
@@ -96,7 +303,26 @@ func (g *CliCommand) Run(ctx context.Context) error {
 	})
 
 	go func() {
-		exit <- eg.Wait()
+		err := eg.Wait()
+		summary.Duplicates = dedup.Dropped()
+
+		if bar != nil {
+			bar.Stop()
+		}
+
+		if checkpoint != nil {
+			closeCheckpoint(checkpoint, err == nil && runCtx.Err() == nil)
+		}
+
+		slog.Info("finished", slog.Any("summary", summary))
+		if !g.cfg.Quiet {
+			fmt.Fprint(os.Stderr, summary.Report())
+		}
+
+		if err == nil && runCtx.Err() == nil {
+			err = g.checkAlive(written)
+		}
+		exit <- err
 	}()
 
 	return <-exit
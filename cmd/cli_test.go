@@ -1,25 +1,56 @@
 package cmd
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"proxy-checker/internal/proxy"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
+func startJudge(t *testing.T) {
+	t.Helper()
+
+	judge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ip":"127.0.0.1","headers":{}}`))
+	}))
+	t.Cleanup(judge.Close)
+
+	os.Setenv("API", judge.URL)
+}
+
 func TestCliCommand_Init(t *testing.T) {
+	startJudge(t)
 	cliCmd := NewCliCommand()
 
-	err := cliCmd.Init([]string{"-i", "stdin", "-o", "stdout", "-c", "10", "-v"})
+	err := cliCmd.Init([]string{"-i", "stdin", "-input-format", "csv", "-o", "stdout", "-format", "csv", "-columns", "proxy, country", "-c", "10", "-v", "-anonymity", "elite", "-max-latency", "800ms", "-sort", "latency", "-min-alive", "20"})
 	if err != nil {
 		t.Fatalf("unexpected error during init: %v", err)
 	}
 
-	if cliCmd.input != "stdin" {
-		t.Errorf("expected input to be input.txt, got %s", cliCmd.input)
+	if len(cliCmd.inputs) != 1 || cliCmd.inputs[0] != "stdin" {
+		t.Errorf("expected inputs to be [stdin], got %v", cliCmd.inputs)
+	}
+
+	if cliCmd.inputFormat != proxy.FormatCSV {
+		t.Errorf("expected input format to be csv, got %s", cliCmd.inputFormat)
 	}
 
 	if cliCmd.output != "stdout" {
 		t.Errorf("expected output to be output.txt, got %s", cliCmd.output)
 	}
 
+	if cliCmd.format != proxy.FormatCSV || len(cliCmd.columns) != 2 || cliCmd.columns[1] != "country" {
+		t.Errorf("expected csv output with columns [proxy country], got %s %v", cliCmd.format, cliCmd.columns)
+	}
+
 	if cliCmd.concurrency != 10 {
 		t.Errorf("expected concurrency to be 10, got %d", cliCmd.concurrency)
 	}
@@ -27,6 +58,113 @@ func TestCliCommand_Init(t *testing.T) {
 	if !cliCmd.verbose {
 		t.Errorf("expected verbose to be true, got false")
 	}
+
+	if cliCmd.anonymity != proxy.Elite {
+		t.Errorf("expected anonymity to be elite, got %s", cliCmd.anonymity)
+	}
+
+	if !cliCmd.cfg.Anonymity {
+		t.Errorf("expected anonymity check to be enabled")
+	}
+
+	if cliCmd.maxLatency != 800*time.Millisecond {
+		t.Errorf("expected max latency to be 800ms, got %s", cliCmd.maxLatency)
+	}
+
+	if cliCmd.sortBy != "latency" {
+		t.Errorf("expected sort to be latency, got %s", cliCmd.sortBy)
+	}
+
+	if cliCmd.minAlive != 20 {
+		t.Errorf("expected min alive to be 20, got %d", cliCmd.minAlive)
+	}
+}
+
+func TestCliCommand_InitInputs(t *testing.T) {
+	startJudge(t)
+	cliCmd := NewCliCommand()
+
+	if err := cliCmd.Init([]string{"-i", "socks4.txt", "-i", "https://example.com/http.txt"}); err != nil {
+		t.Fatalf("unexpected error during init: %v", err)
+	}
+
+	if len(cliCmd.inputs) != 2 || cliCmd.inputs[0] != "socks4.txt" || cliCmd.inputs[1] != "https://example.com/http.txt" {
+		t.Errorf("expected both inputs, got %v", cliCmd.inputs)
+	}
+}
+
+func TestCliCommand_InitInvalidSort(t *testing.T) {
+	if err := NewCliCommand().Init([]string{"-sort", "country"}); err == nil {
+		t.Errorf("expected error for unknown sort key")
+	}
+}
+
+func TestCliCommand_InitInvalidInputFormat(t *testing.T) {
+	if err := NewCliCommand().Init([]string{"-input-format", "xml"}); err == nil {
+		t.Errorf("expected error for unknown input format")
+	}
+}
+
+func TestCliCommand_InitInvalidAnonymity(t *testing.T) {
+	err := NewCliCommand().Init([]string{"-anonymity", "invisible"})
+	if err == nil {
+		t.Errorf("expected error for unknown anonymity level")
+	}
+
+	if code := ExitCode(err); code != ExitInput {
+		t.Errorf("expected exit code %d for invalid flags, got %d", ExitInput, code)
+	}
+}
+
+func TestFilterResults(t *testing.T) {
+	in := make(chan proxy.Result, 3)
+	in <- proxy.Result{Protocols: []string{"http"}, Anonymity: proxy.Elite}
+	in <- proxy.Result{Protocols: []string{"http"}, Anonymity: proxy.Transparent}
+	in <- proxy.Result{Category: proxy.CategoryTimeout}
+	close(in)
+
+	var kept []proxy.Result
+	for res := range filterResults(in, func(res proxy.Result) bool {
+		return !res.OK() || res.Anonymity.AtLeast(proxy.Anonymous)
+	}) {
+		kept = append(kept, res)
+	}
+
+	if len(kept) != 2 || kept[0].Anonymity != proxy.Elite || kept[1].OK() {
+		t.Errorf("unexpected filtered results %+v", kept)
+	}
+}
+
+func TestPartitionResults(t *testing.T) {
+	in := make(chan proxy.Result, 3)
+	in <- proxy.Result{Protocols: []string{"http"}}
+	in <- proxy.Result{Category: proxy.CategoryTimeout}
+	in <- proxy.Result{Category: proxy.CategoryRefused}
+	close(in)
+
+	ok, failed := partitionResults(in, proxy.Result.OK)
+
+	done := make(chan []proxy.Result)
+	go func() {
+		var results []proxy.Result
+		for res := range failed {
+			results = append(results, res)
+		}
+		done <- results
+	}()
+
+	var working []proxy.Result
+	for res := range ok {
+		working = append(working, res)
+	}
+
+	if len(working) != 1 || !working[0].OK() {
+		t.Errorf("unexpected working results %+v", working)
+	}
+
+	if failures := <-done; len(failures) != 2 || failures[1].Category != proxy.CategoryRefused {
+		t.Errorf("unexpected failed results %+v", failures)
+	}
 }
 
 func TestCliCommand_Name(t *testing.T) {
@@ -36,3 +174,163 @@ func TestCliCommand_Name(t *testing.T) {
 		t.Errorf("expected command name to be %s, got %s", expectedName, cliCmd.Name())
 	}
 }
+
+func TestSortResults(t *testing.T) {
+	in := make(chan proxy.Result, 3)
+	in <- proxy.Result{Throughput: 10}
+	in <- proxy.Result{Throughput: 30}
+	in <- proxy.Result{Throughput: 20}
+	close(in)
+
+	var sorted []proxy.Throughput
+	for res := range sortResults(in, func(a, b proxy.Result) bool { return a.Throughput > b.Throughput }) {
+		sorted = append(sorted, res.Throughput)
+	}
+
+	if len(sorted) != 3 || sorted[0] != 30 || sorted[1] != 20 || sorted[2] != 10 {
+		t.Errorf("unexpected order %v", sorted)
+	}
+}
+
+func TestCliCommand_InitQuiet(t *testing.T) {
+	startJudge(t)
+	t.Cleanup(func() { os.Unsetenv("QUIET") })
+
+	cliCmd := NewCliCommand()
+	if err := cliCmd.Init([]string{"-quiet"}); err != nil {
+		t.Fatalf("unexpected error during init: %v", err)
+	}
+
+	if !cliCmd.cfg.Quiet {
+		t.Errorf("expected quiet mode to be enabled")
+	}
+
+	if cliCmd.showProgress() {
+		t.Errorf("expected no progress in quiet mode")
+	}
+}
+
+func TestCountSpecs(t *testing.T) {
+	in := make(chan proxy.Spec)
+	var counted atomic.Int64
+	done := make(chan struct{})
+
+	out := countSpecs(in, func(proxy.Spec) { counted.Add(1) }, func() { close(done) })
+
+	go func() {
+		for _, line := range []string{"127.0.0.1:8080", "127.0.0.1:8081", "127.0.0.1:8082"} {
+			spec, _ := proxy.ParseSpec(line)
+			in <- spec
+		}
+		close(in)
+	}()
+
+	// Nothing is read ahead of the consumer.
+	<-out
+	if n := counted.Load(); n > 2 {
+		t.Errorf("expected input to be read as it is consumed, counted %d", n)
+	}
+
+	ports := []string{"8080"}
+	for spec := range out {
+		ports = append(ports, spec.Port)
+	}
+	<-done
+
+	if strings.Join(ports, ",") != "8080,8081,8082" || counted.Load() != 3 {
+		t.Errorf("expected 3 specs in input order, got %v", ports)
+	}
+}
+
+func TestProgress_Line(t *testing.T) {
+	bar := newProgress(io.Discard)
+	bar.start = time.Now().Add(-10 * time.Second)
+
+	for i := 0; i < 100; i++ {
+		bar.Queue(proxy.Spec{})
+	}
+	for i := 0; i < 20; i++ {
+		bar.Add(proxy.Result{Protocols: []string{"http"}})
+	}
+	for i := 0; i < 30; i++ {
+		bar.Add(proxy.Result{Category: proxy.CategoryTimeout})
+	}
+
+	now := bar.start.Add(10 * time.Second)
+	if line := bar.line(now); line != "checked 50/100+, alive 20, 5.0/s" {
+		t.Errorf("unexpected streaming progress %q", line)
+	}
+
+	bar.InputDone()
+	if line := bar.line(now); line != "checked 50/100, alive 20, 5.0/s, ETA 10s" {
+		t.Errorf("unexpected progress %q", line)
+	}
+}
+
+func TestCliCommand_InitCheckpoint(t *testing.T) {
+	startJudge(t)
+
+	cliCmd := NewCliCommand()
+	if err := cliCmd.Init([]string{"-o", "ok.txt", "-resume"}); err != nil {
+		t.Fatalf("unexpected error during init: %v", err)
+	}
+
+	if cliCmd.checkpoint != "ok.txt.checkpoint" || !cliCmd.resume {
+		t.Errorf("expected to resume from ok.txt.checkpoint, got %q", cliCmd.checkpoint)
+	}
+
+	if err := NewCliCommand().Init([]string{"-resume"}); err == nil {
+		t.Errorf("expected error resuming stdout output without a checkpoint")
+	}
+}
+
+func TestCliCommand_RunResume(t *testing.T) {
+	startJudge(t)
+	t.Cleanup(func() { os.Unsetenv("QUIET") })
+
+	dir := t.TempDir()
+	input, output, failed := filepath.Join(dir, "in.txt"), filepath.Join(dir, "ok.txt"), filepath.Join(dir, "failed.txt")
+
+	if err := os.WriteFile(input, []byte("127.0.0.1:8080\n127.0.0.1:8081\n"), 0o644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	// A run killed before its output was flushed leaves the checkpoint alone.
+	ok, _ := proxy.ParseSpec("127.0.0.1:8080")
+	timedOut, _ := proxy.ParseSpec("127.0.0.1:8081")
+
+	checkpoint, err := proxy.OpenCheckpoint(output+".checkpoint", false)
+	if err != nil {
+		t.Fatalf("failed to open checkpoint: %v", err)
+	}
+	for _, res := range []proxy.Result{
+		{Proxy: ok, Protocols: []string{"http"}},
+		{Proxy: timedOut, Category: proxy.CategoryTimeout, Error: "timeout"},
+	} {
+		if err = checkpoint.Record(res); err != nil {
+			t.Fatalf("failed to record result: %v", err)
+		}
+	}
+	checkpoint.Close()
+
+	cliCmd := NewCliCommand()
+	if err = cliCmd.Init([]string{"-i", input, "-o", output, "-failed", failed, "-resume", "-quiet"}); err != nil {
+		t.Fatalf("unexpected error during init: %v", err)
+	}
+	if err = cliCmd.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error during run: %v", err)
+	}
+
+	for filename, expected := range map[string]string{
+		output: "127.0.0.1:8080\n",
+		failed: "127.0.0.1:8081\ttimeout\ttimeout\n",
+	} {
+		if content, _ := os.ReadFile(filename); string(content) != expected {
+			t.Errorf("expected %s to hold %q, got %q", filepath.Base(filename), expected, content)
+		}
+	}
+
+	if _, err = os.Stat(output + ".checkpoint"); !os.IsNotExist(err) {
+		t.Errorf("expected checkpoint of a complete run to be removed, got %v", err)
+	}
+}
@@ -2,17 +2,32 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"log/slog"
 	"net"
+	"net/url"
 	"os"
 	"os/exec"
 	"proxy-checker/internal/config"
 	"proxy-checker/internal/logger"
+	"proxy-checker/internal/proxy"
 	"runtime"
+	"sort"
+	"strings"
 	"time"
 )
 
+// stringList is a flag that may be repeated, every occurrence adds a value.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func setConcurrencyEnv(concurrency uint) error {
 	if concurrency <= 0 {
 		return nil
@@ -29,6 +44,22 @@ func setVerbosityMode(verbose bool) error {
 	return os.Setenv("VERBOSE", fmt.Sprintf("%t", verbose))
 }
 
+func setQuietMode(quiet bool) error {
+	if !quiet {
+		return nil
+	}
+
+	return os.Setenv("QUIET", fmt.Sprintf("%t", quiet))
+}
+
+func setAnonymityEnv(anonymity proxy.Anonymity) error {
+	if anonymity == proxy.AnonymityUnknown {
+		return nil
+	}
+
+	return os.Setenv("CHECK_ANONYMITY", "true")
+}
+
 func setupLogger(cfg *config.Config) {
 	var l *slog.Logger
 
@@ -36,6 +67,8 @@ func setupLogger(cfg *config.Config) {
 
 	if cfg.Verbose {
 		level = slog.LevelDebug
+	} else if cfg.Quiet {
+		level = slog.LevelWarn
 	}
 
 	switch cfg.Env {
@@ -69,12 +102,154 @@ func open(cfg *config.Config) error {
 	return exec.Command(cmd, append(args, "http://"+cfg.Address)...).Start()
 }
 
-func checkInternetConnection() {
+func checkJudgeConnection(api string) error {
+	u, err := url.Parse(api)
+	if err != nil {
+		return withExitCode(ExitJudgeUnreachable, fmt.Errorf("invalid judge url %s: %w", api, err))
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
 	dialer := &net.Dialer{Timeout: 1 * time.Second}
 
-	conn, err := dialer.Dial("tcp", "8.8.8.8:53")
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return withExitCode(ExitJudgeUnreachable, fmt.Errorf("judge %s is unreachable: %w", api, err))
+	}
+
+	return conn.Close()
+}
+
+// closeCheckpoint removes the checkpoint of a complete run and keeps it otherwise.
+func closeCheckpoint(c *proxy.Checkpoint, complete bool) {
+	var err error
+	if complete {
+		err = c.Remove()
+	} else {
+		err = c.Close()
+	}
+
 	if err != nil {
-		log.Panicf("no internet connection: %s", err)
+		slog.Warn("failed to close checkpoint", slog.String("error", err.Error()))
 	}
-	defer conn.Close()
+}
+
+// countSpecs passes the input through and calls fn for every spec and done once the input is
+// exhausted, the total of a streamed input is known only at its end.
+func countSpecs(in <-chan proxy.Spec, fn func(proxy.Spec), done func()) <-chan proxy.Spec {
+	out := make(chan proxy.Spec)
+
+	go func() {
+		defer close(out)
+		defer done()
+
+		for spec := range in {
+			fn(spec)
+			out <- spec
+		}
+	}()
+
+	return out
+}
+
+// prependResults sends results ahead of those of in.
+func prependResults(results []proxy.Result, in <-chan proxy.Result) <-chan proxy.Result {
+	out := make(chan proxy.Result)
+
+	go func() {
+		defer close(out)
+
+		for _, res := range results {
+			out <- res
+		}
+
+		for res := range in {
+			out <- res
+		}
+	}()
+
+	return out
+}
+
+func filterResults(in <-chan proxy.Result, keep func(proxy.Result) bool) <-chan proxy.Result {
+	out := make(chan proxy.Result)
+
+	go func() {
+		defer close(out)
+
+		for res := range in {
+			if keep(res) {
+				out <- res
+			}
+		}
+	}()
+
+	return out
+}
+
+// partitionResults sends the results matching pred to the first channel and the rest to the
+// second, both channels have to be consumed.
+func partitionResults(in <-chan proxy.Result, pred func(proxy.Result) bool) (<-chan proxy.Result, <-chan proxy.Result) {
+	matched, rest := make(chan proxy.Result), make(chan proxy.Result)
+
+	go func() {
+		defer close(matched)
+		defer close(rest)
+
+		for res := range in {
+			if pred(res) {
+				matched <- res
+			} else {
+				rest <- res
+			}
+		}
+	}()
+
+	return matched, rest
+}
+
+func sortResults(in <-chan proxy.Result, less func(a, b proxy.Result) bool) <-chan proxy.Result {
+	out := make(chan proxy.Result)
+
+	go func() {
+		defer close(out)
+
+		var results []proxy.Result
+		for res := range in {
+			results = append(results, res)
+		}
+
+		sort.SliceStable(results, func(i, j int) bool {
+			return less(results[i], results[j])
+		})
+
+		for _, res := range results {
+			out <- res
+		}
+	}()
+
+	return out
+}
+
+func tapResults(in <-chan proxy.Result, fn func(proxy.Result)) <-chan proxy.Result {
+	out := make(chan proxy.Result)
+
+	go func() {
+		defer close(out)
+
+		for res := range in {
+			fn(res)
+			out <- res
+		}
+	}()
+
+	return out
 }
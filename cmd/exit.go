@@ -0,0 +1,49 @@
+package cmd
+
+import "errors"
+
+// Exit codes of the commands, scripts tell the failures of a run apart by them.
+const (
+	ExitOK               = 0
+	ExitFailure          = 1
+	ExitInput            = 2
+	ExitJudgeUnreachable = 3
+	ExitNoneAlive        = 4
+	ExitTooFewAlive      = 5
+)
+
+// ExitError is an error that ends the process with a specific exit code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &ExitError{Code: code, Err: err}
+}
+
+// ExitCode returns the exit code for the error a command failed with.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	return ExitFailure
+}
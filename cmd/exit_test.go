@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"proxy-checker/internal/proxy"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"success", nil, ExitOK},
+		{"generic", errors.New("failed to write to file"), ExitFailure},
+		{"wrapped", fmt.Errorf("run: %w", withExitCode(ExitInput, errors.New("no such file"))), ExitInput},
+		{"canceled", withExitCode(ExitInput, context.Canceled), ExitInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ExitCode(tt.err); code != tt.expected {
+				t.Errorf("expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestCliCommand_CheckAlive(t *testing.T) {
+	cliCmd := &CliCommand{minAlive: 20}
+
+	tests := []struct {
+		written  uint
+		expected int
+	}{
+		{0, ExitNoneAlive},
+		{12, ExitTooFewAlive},
+		{20, ExitOK},
+	}
+
+	for _, tt := range tests {
+		if code := ExitCode(cliCmd.checkAlive(tt.written)); code != tt.expected {
+			t.Errorf("%d alive: expected exit code %d, got %d", tt.written, tt.expected, code)
+		}
+	}
+}
+
+func TestCheckJudgeConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+
+	if err = checkJudgeConnection("http://" + addr + "/judge"); err != nil {
+		t.Errorf("unexpected error for reachable judge: %v", err)
+	}
+
+	listener.Close()
+
+	if code := ExitCode(checkJudgeConnection("http://" + addr + "/judge")); code != ExitJudgeUnreachable {
+		t.Errorf("expected exit code %d for unreachable judge, got %d", ExitJudgeUnreachable, code)
+	}
+}
+
+func TestCliCommand_InitJudgeFailing(t *testing.T) {
+	judge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "size must be a number", http.StatusBadRequest)
+	}))
+	defer judge.Close()
+
+	os.Setenv("API", judge.URL)
+	defer os.Unsetenv("API")
+
+	if code := ExitCode(NewCliCommand().Init([]string{"-o", "stdout"})); code != ExitJudgeUnreachable {
+		t.Errorf("expected exit code %d for a judge failing to serve our address, got %d", ExitJudgeUnreachable, code)
+	}
+}
+
+func TestCliCommand_InitAnonymityTextJudge(t *testing.T) {
+	judge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer judge.Close()
+
+	os.Setenv("API", judge.URL)
+	defer os.Unsetenv("API")
+	defer os.Unsetenv("CHECK_ANONYMITY")
+
+	err := NewCliCommand().Init([]string{"-o", "stdout", "-anonymity", "anonymous"})
+	if code := ExitCode(err); code != ExitInput || !errors.Is(err, proxy.ErrJudgeHeaders) {
+		t.Errorf("expected exit code %d for -anonymity with a text judge, got %d: %v", ExitInput, code, err)
+	}
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"golang.org/x/sync/errgroup"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"proxy-checker/internal/config"
+	http_server "proxy-checker/internal/http-server"
+	"syscall"
+)
+
+type JudgeCommand struct {
+	fs  *flag.FlagSet
+	cfg *config.Config
+
+	verbose  bool
+	certFile string
+	keyFile  string
+}
+
+func NewJudgeCommand() *JudgeCommand {
+	gc := &JudgeCommand{
+		fs: flag.NewFlagSet("judge", flag.ContinueOnError),
+	}
+
+	gc.fs.BoolVar(&gc.verbose, "v", false, "verbosity mode")
+	gc.fs.StringVar(&gc.certFile, "cert", "", "TLS certificate file, serves HTTPS together with -key")
+	gc.fs.StringVar(&gc.keyFile, "key", "", "TLS private key file")
+
+	return gc
+}
+
+func (g *JudgeCommand) Name() string {
+	return g.fs.Name()
+}
+
+func (g *JudgeCommand) Init(args []string) error {
+	if err := g.fs.Parse(args); err != nil {
+		return err
+	}
+
+	if (g.certFile == "") != (g.keyFile == "") {
+		return errors.New("-cert and -key must be set together")
+	}
+
+	if err := setVerbosityMode(g.verbose); err != nil {
+		return err
+	}
+
+	g.cfg = config.MustLoad()
+
+	setupLogger(g.cfg)
+
+	slog.Info("starting judge", slog.String("env", g.cfg.Env), slog.Bool("tls", g.certFile != ""))
+	slog.Debug("debug enabled")
+
+	return nil
+}
+
+func (g *JudgeCommand) Run(ctx context.Context) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+
+	srv := &http.Server{
+		Addr:         g.cfg.Address,
+		Handler:      http_server.NewJudge(g.cfg),
+		ReadTimeout:  g.cfg.HTTPServer.Timeout,
+		WriteTimeout: g.cfg.HTTPServer.Timeout,
+		IdleTimeout:  g.cfg.HTTPServer.IdleTimeout,
+	}
+
+	var eg errgroup.Group
+
+	eg.Go(func() error {
+		slog.Info("listening on " + g.cfg.Address)
+
+		var err error
+		if g.certFile != "" {
+			err = srv.ListenAndServeTLS(g.certFile, g.keyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		return nil
+	})
+
+	eg.Go(func() error {
+		<-stop
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, g.cfg.ShutdownTimeout)
+		defer cancel()
+
+		return srv.Shutdown(shutdownCtx)
+	})
+
+	return eg.Wait()
+}
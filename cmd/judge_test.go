@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestJudgeCommand_Init(t *testing.T) {
+	judgeCmd := NewJudgeCommand()
+
+	if err := judgeCmd.Init([]string{"-v"}); err != nil {
+		t.Fatalf("unexpected error during init: %v", err)
+	}
+
+	if !judgeCmd.verbose {
+		t.Errorf("expected verbose to be true, got false")
+	}
+}
+
+func TestJudgeCommand_InitCertWithoutKey(t *testing.T) {
+	if err := NewJudgeCommand().Init([]string{"-cert", "cert.pem"}); err == nil {
+		t.Errorf("expected error when -key is missing")
+	}
+}
+
+func TestJudgeCommand_Name(t *testing.T) {
+	judgeCmd := NewJudgeCommand()
+	expectedName := "judge"
+	if judgeCmd.Name() != expectedName {
+		t.Errorf("expected command name to be %s, got %s", expectedName, judgeCmd.Name())
+	}
+}
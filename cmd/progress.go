@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"proxy-checker/internal/proxy"
+	"sync/atomic"
+	"time"
+)
+
+// progress draws a status line of the run: checked out of queued proxies, alive ones, the
+// check rate and, once the whole input has been read, the estimated time left.
+type progress struct {
+	w     io.Writer
+	start time.Time
+	stop  chan struct{}
+	done  chan struct{}
+
+	queued  atomic.Int64
+	checked atomic.Int64
+	alive   atomic.Int64
+	read    atomic.Bool
+}
+
+func newProgress(w io.Writer) *progress {
+	return &progress{w: w, start: time.Now(), stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *progress) Queue(proxy.Spec) {
+	p.queued.Add(1)
+}
+
+// InputDone marks the number of queued proxies as final.
+func (p *progress) InputDone() {
+	p.read.Store(true)
+}
+
+func (p *progress) Add(res proxy.Result) {
+	p.checked.Add(1)
+
+	if res.OK() {
+		p.alive.Add(1)
+	}
+}
+
+// Start redraws the status line in the background until Stop is called.
+func (p *progress) Start() {
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				fmt.Fprint(p.w, "\r\033[K")
+				return
+			case now := <-ticker.C:
+				fmt.Fprintf(p.w, "\r\033[K%s", p.line(now))
+			}
+		}
+	}()
+}
+
+// Stop clears the status line, the terminal is free for other output once it returns.
+func (p *progress) Stop() {
+	close(p.stop)
+	<-p.done
+}
+
+func (p *progress) line(now time.Time) string {
+	checked, queued, alive := p.checked.Load(), p.queued.Load(), p.alive.Load()
+
+	total := fmt.Sprintf("%d", queued)
+	if !p.read.Load() {
+		// Streamed input, more proxies may still come.
+		total += "+"
+	}
+
+	var rate float64
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		rate = float64(checked) / elapsed
+	}
+
+	line := fmt.Sprintf("checked %d/%s, alive %d, %.1f/s", checked, total, alive, rate)
+
+	if p.read.Load() && rate > 0 && queued > checked {
+		eta := time.Duration(float64(queued-checked) / rate * float64(time.Second))
+		line += ", ETA " + eta.Round(time.Second).String()
+	}
+
+	return line
+}
@@ -56,7 +56,9 @@ func (g *ServerCommand) Init(args []string) error {
 	g.temp = template.Must(template.ParseGlob("web/templates/*"))
 
 	setupLogger(g.cfg)
-	checkInternetConnection()
+	if err := checkJudgeConnection(g.cfg.API); err != nil {
+		return err
+	}
 
 	slog.Info("starting", slog.String("env", g.cfg.Env))
 	slog.Debug("debug enabled")
@@ -9,6 +9,7 @@ import (
 type Config struct {
 	Env     string `envconfig:"ENV" default:"local"`
 	Verbose bool   `envconfig:"VERBOSE"`
+	Quiet   bool   `envconfig:"QUIET"`
 	HTTPServer
 	ProxyChecker
 	TelegramBot
@@ -28,8 +29,28 @@ type HTTPServer struct {
 
 type ProxyChecker struct {
 	API         string        `envconfig:"API" default:"http://checkip.amazonaws.com"`
+	TLSAPI      string        `envconfig:"TLS_API" default:"https://checkip.amazonaws.com"`
+	TLSCA       string        `envconfig:"TLS_CA"`
 	Timeout     time.Duration `envconfig:"CHECKING_TIMEOUT" default:"3600ms"`
+	DialTimeout time.Duration `envconfig:"DIAL_TIMEOUT" default:"3s"`
+	MaxConns    uint          `envconfig:"MAX_CONNS" default:"512"`
 	Concurrency uint          `envconfig:"CONCURRENCY" default:"100"`
+	Protocols   []string      `envconfig:"PROTOCOLS" default:"http,socks4,socks5"`
+	Anonymity   bool          `envconfig:"CHECK_ANONYMITY"`
+	Resolve     bool          `envconfig:"RESOLVE"`
+
+	ThroughputURL  string `envconfig:"THROUGHPUT_URL"`
+	ThroughputSize int64  `envconfig:"THROUGHPUT_SIZE" default:"1048576"`
+
+	RetryAttempts uint          `envconfig:"RETRY_ATTEMPTS" default:"1"`
+	RetryBackoff  time.Duration `envconfig:"RETRY_BACKOFF" default:"500ms"`
+	RetryOn       []string      `envconfig:"RETRY_ON" default:"timeout,unreachable"`
+
+	RateLimit         float64 `envconfig:"RATE_LIMIT"`
+	HostConcurrency   uint    `envconfig:"HOST_CONCURRENCY"`
+	SubnetConcurrency uint    `envconfig:"SUBNET_CONCURRENCY"`
+	SubnetPrefix      int     `envconfig:"SUBNET_PREFIX" default:"24"`
+	Subnet6Prefix     int     `envconfig:"SUBNET6_PREFIX" default:"64"`
 }
 
 func MustLoad() *Config {
@@ -22,8 +22,14 @@ func TestMustLoad_Defaults(t *testing.T) {
 	assert.Equal(int64(1048576), cfg.HTTPServer.MaxRequestSize)
 	assert.Equal(10*time.Second, cfg.HTTPServer.ShutdownTimeout)
 	assert.Equal("http://checkip.amazonaws.com", cfg.ProxyChecker.API)
+	assert.Equal("https://checkip.amazonaws.com", cfg.ProxyChecker.TLSAPI)
 	assert.Equal(3600*time.Millisecond, cfg.ProxyChecker.Timeout)
+	assert.Equal(3*time.Second, cfg.ProxyChecker.DialTimeout)
 	assert.Equal(uint(100), cfg.ProxyChecker.Concurrency)
+	assert.Equal([]string{"http", "socks4", "socks5"}, cfg.ProxyChecker.Protocols)
+	assert.Equal(uint(1), cfg.ProxyChecker.RetryAttempts)
+	assert.Equal(500*time.Millisecond, cfg.ProxyChecker.RetryBackoff)
+	assert.Equal([]string{"timeout", "unreachable"}, cfg.ProxyChecker.RetryOn)
 	assert.Equal("", cfg.TelegramBot.APIToken)
 }
 
@@ -35,6 +41,8 @@ func TestMustLoad_EnvVariables(t *testing.T) {
 	os.Setenv("TELEGRAM_API_TOKEN", "test-token")
 	os.Setenv("SHUTDOWN_TIMEOUT", "15s")
 	os.Setenv("CHECKING_TIMEOUT", "5s")
+	os.Setenv("PROTOCOLS", "socks4,socks4a")
+	os.Setenv("TLS_CA", "/etc/judge/ca.pem")
 
 	cfg := MustLoad()
 
@@ -43,5 +51,7 @@ func TestMustLoad_EnvVariables(t *testing.T) {
 	assert.True(cfg.Verbose)
 	assert.Equal(15*time.Second, cfg.HTTPServer.ShutdownTimeout)
 	assert.Equal(5*time.Second, cfg.ProxyChecker.Timeout)
+	assert.Equal([]string{"socks4", "socks4a"}, cfg.ProxyChecker.Protocols)
+	assert.Equal("/etc/judge/ca.pem", cfg.ProxyChecker.TLSCA)
 	assert.Equal("test-token", cfg.TelegramBot.APIToken)
 }
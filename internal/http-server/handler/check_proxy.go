@@ -92,10 +92,11 @@ func ProxyCheckForm(template *template.Template) http.HandlerFunc {
 	}
 }
 
-func sendProxiesToChannel(proxies []string) chan string {
-	proxiesCh := make(chan string, len(proxies))
+func sendProxiesToChannel(proxies []string) chan proxy.Spec {
+	proxiesCh := make(chan proxy.Spec, len(proxies))
 	for _, p := range proxies {
-		proxiesCh <- p
+		spec, _ := proxy.ParseSpec(strings.TrimSpace(p))
+		proxiesCh <- spec
 	}
 	close(proxiesCh)
 	return proxiesCh
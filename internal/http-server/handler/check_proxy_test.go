@@ -6,6 +6,7 @@ import (
 	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"proxy-checker/internal/proxy"
 	"strings"
 	"testing"
 
@@ -18,19 +19,23 @@ type MockChecker struct {
 	mock.Mock
 }
 
-func (m *MockChecker) CheckOne(ctx context.Context, line string) (string, error) {
-	args := m.Called(ctx, line)
-	return args.String(0), args.Error(1)
+func (m *MockChecker) CheckJudge() error {
+	return m.Called().Error(0)
 }
 
-func (m *MockChecker) Check(ctx context.Context, proxies <-chan string) (<-chan string, <-chan error) {
+func (m *MockChecker) CheckOne(ctx context.Context, spec proxy.Spec) (proxy.Result, error) {
+	args := m.Called(ctx, spec)
+	return args.Get(0).(proxy.Result), args.Error(1)
+}
+
+func (m *MockChecker) Check(ctx context.Context, proxies <-chan proxy.Spec) (<-chan proxy.Result, <-chan error) {
 	args := m.Called(ctx, proxies)
-	return args.Get(0).(<-chan string), args.Get(1).(<-chan error)
+	return args.Get(0).(<-chan proxy.Result), args.Get(1).(<-chan error)
 }
 
-func (m *MockChecker) AwaitCheck(ctx context.Context, proxiesCh <-chan string) ([]string, error) {
+func (m *MockChecker) AwaitCheck(ctx context.Context, proxiesCh <-chan proxy.Spec) ([]proxy.Result, error) {
 	args := m.Called(ctx, proxiesCh)
-	return args.Get(0).([]string), args.Error(1)
+	return args.Get(0).([]proxy.Result), args.Error(1)
 }
 
 func TestProxyRequest_Validate(t *testing.T) {
@@ -102,7 +107,7 @@ func TestProxyCheckAPI(t *testing.T) {
 		req, err := http.NewRequest("POST", "/api/check", strings.NewReader(requestBody))
 		assert.NoError(t, err)
 
-		mockChecker.On("AwaitCheck", mock.Anything, mock.Anything).Return([]string{}, errors.New("some error API"))
+		mockChecker.On("AwaitCheck", mock.Anything, mock.Anything).Return([]proxy.Result{}, errors.New("some error API"))
 
 		rr := httptest.NewRecorder()
 		handlerFunc.ServeHTTP(rr, req)
@@ -119,18 +124,21 @@ func TestProxyCheckAPI(t *testing.T) {
 		req, err := http.NewRequest("POST", "/api/check", strings.NewReader(requestBody))
 		assert.NoError(t, err)
 
-		mockChecker.On("AwaitCheck", mock.Anything, mock.Anything).Return([]string{"192.168.0.1:8080"}, nil)
+		mockChecker.On("AwaitCheck", mock.Anything, mock.Anything).Return([]proxy.Result{
+			{Proxy: proxy.Spec{Host: "192.168.0.1", Port: "8080"}, Protocols: []string{"http"}},
+		}, nil)
 
 		rr := httptest.NewRecorder()
 		handlerFunc.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"proxy":"192.168.0.1:8080","protocols":["http"]`)
 		mockChecker.AssertExpectations(t)
 	})
 }
 
 func TestProxyCheckWeb(t *testing.T) {
-	tmpl := template.Must(template.New("proxies_table.html.tmpl").Parse("{{.}}"))
+	tmpl := template.Must(template.New("proxies_table.html.tmpl").Parse("{{range .}}[{{.Proxy}}]{{end}}"))
 
 	t.Run("Missing proxies parameter", func(t *testing.T) {
 		mockChecker := new(MockChecker)
@@ -166,7 +174,7 @@ func TestProxyCheckWeb(t *testing.T) {
 		assert.NoError(t, err)
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-		mockChecker.On("AwaitCheck", mock.Anything, mock.Anything).Return([]string{}, errors.New("some error Web"))
+		mockChecker.On("AwaitCheck", mock.Anything, mock.Anything).Return([]proxy.Result{}, errors.New("some error Web"))
 
 		rr := httptest.NewRecorder()
 		handlerFunc.ServeHTTP(rr, req)
@@ -184,13 +192,16 @@ func TestProxyCheckWeb(t *testing.T) {
 		assert.NoError(t, err)
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-		mockChecker.On("AwaitCheck", mock.Anything, mock.Anything).Return([]string{"192.168.0.1:8080", "192.168.0.1:8010"}, nil)
+		mockChecker.On("AwaitCheck", mock.Anything, mock.Anything).Return([]proxy.Result{
+			{Proxy: proxy.Spec{Host: "192.168.0.1", Port: "8080"}, Protocols: []string{"http"}},
+			{Proxy: proxy.Spec{Host: "192.168.0.1", Port: "8010"}, Protocols: []string{"socks5"}},
+		}, nil)
 
 		rr := httptest.NewRecorder()
 		handlerFunc.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusOK, rr.Code)
-		assert.Equal(t, "[192.168.0.1:8080 192.168.0.1:8010]", rr.Body.String())
+		assert.Equal(t, "[192.168.0.1:8080][192.168.0.1:8010]", rr.Body.String())
 		mockChecker.AssertExpectations(t)
 	})
 }
@@ -216,7 +227,8 @@ func TestSendProxiesToChannel(t *testing.T) {
 
 	for _, expected := range proxies {
 		actual := <-proxiesCh
-		assert.Equal(t, expected, actual)
+		assert.Equal(t, expected, actual.String())
+		assert.NoError(t, actual.Err())
 	}
 	_, ok := <-proxiesCh
 	assert.False(t, ok, "channel should be closed")
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"proxy-checker/internal/proxy"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPayloadSize = 1 << 20
+	maxPayloadSize     = 16 << 20
+)
+
+func Judge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		receivedAt := time.Now()
+		_, _ = io.Copy(io.Discard, r.Body)
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		respondWithSuccess(w, r, proxy.JudgeResponse{
+			IP:         ip,
+			Headers:    r.Header,
+			TLS:        proxy.NewJudgeTLS(r.TLS),
+			ReceivedAt: receivedAt,
+			Duration:   time.Since(receivedAt),
+		})
+	}
+}
+
+func Payload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		size := int64(defaultPayloadSize)
+
+		if s := r.URL.Query().Get("size"); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 || n > maxPayloadSize {
+				renderError(w, r, fmt.Sprintf("size must be between 0 and %d", maxPayloadSize), http.StatusBadRequest)
+				return
+			}
+			size = n
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+
+		_, _ = io.CopyN(w, zeros{}, size)
+	}
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
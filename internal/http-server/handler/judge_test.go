@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"proxy-checker/internal/proxy"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJudge(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/judge", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	req.Header.Set("Via", "1.1 squid")
+	req.Header.Set("X-Forwarded-For", "10.0.0.2")
+
+	rr := httptest.NewRecorder()
+	Judge().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp proxy.JudgeResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "10.0.0.1", resp.IP)
+	assert.Equal(t, "1.1 squid", resp.Headers.Get("Via"))
+	assert.Equal(t, "10.0.0.2", resp.Headers.Get("X-Forwarded-For"))
+	assert.Nil(t, resp.TLS)
+	assert.False(t, resp.ReceivedAt.IsZero())
+}
+
+func TestJudge_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(Judge())
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var judgement proxy.JudgeResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&judgement))
+	assert.Equal(t, "127.0.0.1", judgement.IP)
+	if assert.NotNil(t, judgement.TLS) {
+		assert.Equal(t, "TLS 1.3", judgement.TLS.Version)
+		assert.NotEmpty(t, judgement.TLS.CipherSuite)
+	}
+}
+
+func TestPayload(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSize   int
+	}{
+		{"explicit size", "?size=1024", http.StatusOK, 1024},
+		{"empty payload", "?size=0", http.StatusOK, 0},
+		{"default size", "", http.StatusOK, 1 << 20},
+		{"invalid size", "?size=abc", http.StatusBadRequest, -1},
+		{"too large", "?size=16777217", http.StatusBadRequest, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/payload"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			Payload().ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedSize >= 0 {
+				assert.Equal(t, tt.expectedSize, rr.Body.Len())
+			}
+		})
+	}
+}
@@ -19,7 +19,21 @@ func New(cfg *config.Config, template *template.Template) http.Handler {
 		proxy.NewChecker(cfg.ProxyChecker),
 	)
 
-	var h http.Handler = mux
+	return wrap(cfg, mux)
+}
+
+func NewJudge(cfg *config.Config) http.Handler {
+	mux := http.NewServeMux()
+
+	addJudgeRoutes(mux)
+	// The payload is served by the judge only, it is too costly for the public server.
+	mux.Handle("GET /payload", handler.Payload())
+	mux.Handle("GET /", handler.Judge())
+
+	return wrap(cfg, mux)
+}
+
+func wrap(cfg *config.Config, h http.Handler) http.Handler {
 	h = middleware.Logging(h)
 	h = middleware.RequestSizing(cfg.MaxRequestSize, h)
 
@@ -33,9 +47,14 @@ func addRoutes(
 ) {
 	mux.Handle("POST /api/v1/check", middleware.RateLimiting(3*time.Minute, handler.ProxyCheckAPI(checker)))
 	mux.Handle("POST /check", middleware.RateLimiting(3*time.Minute, handler.ProxyCheckWeb(temp, checker)))
+	mux.Handle("GET /", handler.ProxyCheckForm(temp))
+	addJudgeRoutes(mux)
+}
+
+func addJudgeRoutes(mux *http.ServeMux) {
 	mux.Handle("GET /healthz", handleHealthz())
 	mux.Handle("GET /ip", handleIP())
-	mux.Handle("GET /", handler.ProxyCheckForm(temp))
+	mux.Handle("GET /judge", handler.Judge())
 }
 
 func handleHealthz() http.HandlerFunc {
@@ -41,3 +41,49 @@ func TestHandleIP(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, rr.Body.String(), req.RemoteAddr)
 }
+
+func TestHandleJudge(t *testing.T) {
+	req := httptest.NewRequest("GET", "/judge", nil)
+	rr := httptest.NewRecorder()
+
+	handler := New(&config.Config{}, template.New(""))
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `"ip":"192.0.2.1"`)
+}
+
+func TestNewJudge(t *testing.T) {
+	handler := NewJudge(&config.Config{})
+
+	for _, path := range []string{"/", "/judge"} {
+		req := httptest.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"ip":"192.0.2.1"`)
+	}
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "I'm alive", rr.Body.String())
+}
+
+func TestHandlePayload(t *testing.T) {
+	req := httptest.NewRequest("GET", "/payload?size=16", nil)
+
+	rr := httptest.NewRecorder()
+	NewJudge(&config.Config{}).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 16, rr.Body.Len())
+
+	rr = httptest.NewRecorder()
+	New(&config.Config{}, template.New("")).ServeHTTP(rr, req)
+
+	assert.NotEqual(t, "application/octet-stream", rr.Header().Get("Content-Type"), "payload should be served by the judge only")
+}
@@ -2,45 +2,140 @@ package proxy
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
+	"net"
 	"net/http"
-	"net/url"
+	"net/http/httptrace"
+	"os"
 	"proxy-checker/internal/config"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
 )
 
-var pattern = regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}:\d{1,5}\b`)
-
 type Checker interface {
-	CheckOne(ctx context.Context, line string) (string, error)
-	Check(ctx context.Context, proxies <-chan string) (<-chan string, <-chan error)
-	AwaitCheck(ctx context.Context, proxiesCh <-chan string) ([]string, error)
+	// CheckJudge asks the judge for our own addresses ahead of the checks, a judge failing
+	// to serve them would fail every proxy.
+	CheckJudge() error
+	CheckOne(ctx context.Context, spec Spec) (Result, error)
+	Check(ctx context.Context, proxies <-chan Spec) (<-chan Result, <-chan error)
+	AwaitCheck(ctx context.Context, proxiesCh <-chan Spec) ([]Result, error)
 }
 
+var defaultProtocols = []string{"http", "socks4", "socks5"}
+
 type DefaultChecker struct {
-	Target      string
-	Timeout     time.Duration
-	Concurrency uint
-	realIP      string
-	once        sync.Once
+	Target         string
+	TLSTarget      string
+	ThroughputURL  string
+	ThroughputSize int64
+	Timeout        time.Duration
+	DialTimeout    time.Duration
+	Concurrency    uint
+	Protocols      []string
+	Anonymity      bool
+	Resolve        bool
+	Retry          RetryPolicy
+	limits         *limits
+	realIPs        []string
+	realIPErr      error
+	once           sync.Once
+	tlsConfig      *tls.Config
+	tlsErr         error
 }
 
 func NewChecker(cfg config.ProxyChecker) Checker {
+	protocols := cfg.Protocols
+	if len(protocols) == 0 {
+		protocols = defaultProtocols
+	}
+
+	// A dial must not outlive the request, the connection slot is released once it ends.
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout <= 0 || (cfg.Timeout > 0 && dialTimeout > cfg.Timeout) {
+		dialTimeout = cfg.Timeout
+	}
+
+	tlsConfig, tlsErr := newTLSConfig(cfg.TLSCA)
+	if tlsErr != nil {
+		slog.Error("failed to load TLS_CA", slog.String("error", tlsErr.Error()))
+	}
+
 	return &DefaultChecker{
-		Target:      cfg.API,
-		Timeout:     cfg.Timeout,
-		Concurrency: cfg.Concurrency,
+		Target:         cfg.API,
+		TLSTarget:      cfg.TLSAPI,
+		ThroughputURL:  cfg.ThroughputURL,
+		ThroughputSize: cfg.ThroughputSize,
+		Timeout:        cfg.Timeout,
+		DialTimeout:    dialTimeout,
+		Concurrency:    cfg.Concurrency,
+		Protocols:      protocols,
+		Anonymity:      cfg.Anonymity,
+		Resolve:        cfg.Resolve,
+		Retry:          newRetryPolicy(cfg),
+		limits:         newLimits(cfg),
+		tlsConfig:      tlsConfig,
+		tlsErr:         tlsErr,
+	}
+}
+
+// newTLSConfig trusts the certificates of caFile besides those of the system, e.g. of a
+// judge serving a self-signed certificate in a lab.
+func newTLSConfig(caFile string) (*tls.Config, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+
+	filename, err := expandPath(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates in %s", filename)
+	}
+
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+func newRetryPolicy(cfg config.ProxyChecker) RetryPolicy {
+	retryable := make([]Category, 0, len(cfg.RetryOn))
+	for _, name := range cfg.RetryOn {
+		c, err := ParseCategory(name)
+		if err != nil {
+			slog.Warn("ignoring retry category", slog.String("error", err.Error()))
+			continue
+		}
+
+		retryable = append(retryable, c)
+	}
+
+	return RetryPolicy{
+		Attempts:  cfg.RetryAttempts,
+		Backoff:   cfg.RetryBackoff,
+		Retryable: retryable,
 	}
 }
 
-func (c *DefaultChecker) AwaitCheck(ctx context.Context, proxiesCh <-chan string) ([]string, error) {
-	var err error
-	res := make([]string, 0, len(proxiesCh))
+func (c *DefaultChecker) AwaitCheck(ctx context.Context, proxiesCh <-chan Spec) ([]Result, error) {
+	res := make([]Result, 0, len(proxiesCh))
 
 	resCh, errCh := c.Check(ctx, proxiesCh)
 
@@ -48,10 +143,15 @@ func (c *DefaultChecker) AwaitCheck(ctx context.Context, proxiesCh <-chan string
 		select {
 		case proxy, ok := <-resCh:
 			if !ok {
-				return res, err
+				return res, nil
 			}
 			res = append(res, proxy)
-		case err = <-errCh:
+		case err, ok := <-errCh:
+			if !ok {
+				// Closed along with resCh, keep draining the results.
+				errCh = nil
+				continue
+			}
 			return res, err
 		case <-ctx.Done():
 			return res, ctx.Err()
@@ -59,10 +159,10 @@ func (c *DefaultChecker) AwaitCheck(ctx context.Context, proxiesCh <-chan string
 	}
 }
 
-func (c *DefaultChecker) Check(ctx context.Context, proxiesCh <-chan string) (<-chan string, <-chan error) {
+func (c *DefaultChecker) Check(ctx context.Context, proxiesCh <-chan Spec) (<-chan Result, <-chan error) {
 	var wg sync.WaitGroup
 	errCh := make(chan error, 1)
-	resCh := make(chan string, c.Concurrency)
+	resCh := make(chan Result, c.Concurrency)
 
 	for i := uint(0); i < c.Concurrency; i++ {
 		wg.Add(1)
@@ -71,13 +171,13 @@ func (c *DefaultChecker) Check(ctx context.Context, proxiesCh <-chan string) (<-
 
 			for {
 				select {
-				case ch, ok := <-proxiesCh:
+				case spec, ok := <-proxiesCh:
 					if !ok {
 						return
 					}
 
-					if p, err := c.CheckOne(ctx, ch); err == nil {
-						resCh <- p
+					for _, res := range c.checkResolved(ctx, spec) {
+						resCh <- res
 					}
 				case <-ctx.Done():
 					return
@@ -95,91 +195,376 @@ func (c *DefaultChecker) Check(ctx context.Context, proxiesCh <-chan string) (<-
 	return resCh, errCh
 }
 
-func (c *DefaultChecker) CheckOne(ctx context.Context, line string) (string, error) {
-	var proxy string
-	if proxy = pattern.FindString(line); proxy == "" {
-		return proxy, fmt.Errorf("invalid proxy url: %s", line)
+// checkResolved checks spec, with Resolve set a hostname is checked once for every address
+// it resolves to.
+func (c *DefaultChecker) checkResolved(ctx context.Context, spec Spec) []Result {
+	if spec.Err() != nil || !c.Resolve || !spec.isHostname() {
+		res, _ := c.CheckOne(ctx, spec)
+		return []Result{res}
 	}
 
-	var err error
-	c.once.Do(func() {
-		c.realIP, err = c.getRealIP()
-	})
+	lookupCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
 
+	addrs, err := net.DefaultResolver.LookupHost(lookupCtx, spec.Host)
 	if err != nil {
-		return proxy, fmt.Errorf("failed to get real IP: %w", err)
+		res, _ := Result{Proxy: spec, Source: spec.Source, CheckedAt: time.Now()}.failed(err)
+		return []Result{res}
 	}
 
-	r := make(chan error)
+	results := make([]Result, 0, len(addrs))
+	for _, addr := range addrs {
+		resolved := spec
+		resolved.Host = addr
 
-	fn := func(schema string) {
-		log := slog.With(slog.String("schema", schema), slog.String("proxy", proxy))
-		log.Debug("start proxy checking")
+		res, _ := c.CheckOne(ctx, resolved)
+		results = append(results, res)
+	}
 
-		now := time.Now()
-		err := c.doRequest(ctx, schema, proxy)
-		log.Debug("proxy checking finished",
-			slog.String("error", errToStr(err)),
-			slog.String("duration", time.Since(now).String()),
-		)
+	return results
+}
 
-		r <- err
+func (c *DefaultChecker) CheckOne(ctx context.Context, spec Spec) (Result, error) {
+	res := Result{Proxy: spec, Source: spec.Source, CheckedAt: time.Now()}
+
+	if spec.Err() != nil {
+		return res.failed(spec.Err())
 	}
 
-	go fn("http")
-	go fn("socks5")
+	realIPs, err := c.loadRealIPs()
+	if err != nil {
+		return res.failed(fmt.Errorf("failed to get real IP: %w", err))
+	}
 
-	for i := 0; i < 2; i++ {
-		if err = <-r; err == nil {
-			return proxy, nil
+	protocols := c.Protocols
+	if protocol, ok := spec.Protocol(); ok {
+		protocols = []string{protocol}
+	}
+
+	attempts := make([]attempt, len(protocols))
+
+	var wg sync.WaitGroup
+	for i, schema := range protocols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			attempts[i] = c.checkProtocol(ctx, schema, spec)
+		}()
+	}
+	wg.Wait()
+
+	errs := make([]error, 0, len(attempts))
+	for _, a := range attempts {
+		if a.err != nil {
+			errs = append(errs, a.err)
+			continue
 		}
+
+		if res.Latency == nil {
+			res.Latency = make(map[string]Timing, len(attempts))
+		}
+
+		res.Protocols = append(res.Protocols, a.schema)
+		res.Latency[a.schema] = a.timing
+
+		if res.Attempts == 0 || a.tries < res.Attempts {
+			res.Attempts = a.tries
+		}
+
+		if res.ExitIP == "" {
+			res.ExitIP = a.judgement.IP
+		}
+
+		if c.Anonymity {
+			// A proxy is only as anonymous as its leakiest protocol.
+			if anonymity := a.judgement.Anonymity(realIPs...); res.Anonymity == AnonymityUnknown || !anonymity.AtLeast(res.Anonymity) {
+				res.Anonymity = anonymity
+			}
+		}
+	}
+
+	if !res.OK() {
+		return res.failed(mostTelling(errs))
 	}
 
-	return proxy, err
+	if c.ThroughputURL != "" {
+		res.Throughput = c.measureThroughput(ctx, res.FastestProtocol(), spec)
+	}
+
+	return res, nil
 }
 
-func (c *DefaultChecker) doRequest(ctx context.Context, schema, proxy string) error {
-	proxyURL := http.ProxyURL(&url.URL{
-		Host:   proxy,
-		Scheme: schema,
+func (c *DefaultChecker) checkProtocol(ctx context.Context, schema string, spec Spec) attempt {
+	log := slog.With(slog.String("schema", schema), slog.Any("proxy", spec))
+
+	a := attempt{schema: schema}
+
+	// Retries reuse the connection of the previous attempt when the proxy kept it open.
+	client, closeClient, err := c.openClient(ctx, schema, spec)
+	if err != nil {
+		a.tries, a.err = 1, classifyError(err)
+		return a
+	}
+	defer closeClient()
+
+	for a.tries = 1; ; a.tries++ {
+		log.Debug("start proxy checking", slog.Uint64("attempt", uint64(a.tries)))
+
+		release, err := c.limits.acquire(ctx, spec.Host)
+		if err != nil {
+			a.err = classifyError(err)
+			return a
+		}
+
+		a.judgement, a.timing, a.err = c.doRequest(ctx, client, schema, spec)
+		a.err = classifyError(a.err)
+		release()
+
+		log.Debug("proxy checking finished",
+			slog.String("error", errToStr(a.err)),
+			slog.String("duration", a.timing.Total.String()),
+		)
+
+		if !c.Retry.retry(a.tries, a.err) {
+			return a
+		}
+
+		select {
+		case <-time.After(c.Retry.delay(a.tries)):
+		case <-ctx.Done():
+			return a
+		}
+	}
+}
+
+func (c *DefaultChecker) doRequest(ctx context.Context, client *http.Client, schema string, proxy Spec) (JudgeResponse, Timing, error) {
+	target := c.Target
+	if schema == "connect" {
+		target = c.TLSTarget
+	}
+
+	trace := &requestTrace{start: time.Now()}
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		ConnectDone:          trace.connectDone,
+		GotFirstResponseByte: trace.gotFirstResponseByte,
 	})
 
-	client := &http.Client{
-		Transport: &http.Transport{
-			Proxy: proxyURL,
-		},
-		Timeout: c.Timeout,
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return JudgeResponse{}, trace.timing(), fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return JudgeResponse{}, trace.timing(), fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusProxyAuthRequired:
+		return JudgeResponse{}, trace.timing(), fmt.Errorf("%w: %d", ErrAuthRequired, resp.StatusCode)
+	default:
+		return JudgeResponse{}, trace.timing(), fmt.Errorf("%w: %d", ErrBadStatus, resp.StatusCode)
+	}
+
+	judgement, err := c.readJudgement(resp)
+	timing := trace.timing()
+	timing.Total = time.Since(trace.start)
+
+	if err != nil {
+		return judgement, timing, err
+	}
+
+	if realIPs, _ := c.loadRealIPs(); isRealIP(judgement.IP, realIPs) {
+		return judgement, timing, fmt.Errorf("%w: %s", ErrIPLeak, proxy.Address())
+	}
+
+	return judgement, timing, nil
+}
+
+// requestTrace records the timing of a request. Its hooks run on the goroutines of the
+// transport, a dial may still complete after the request has failed.
+type requestTrace struct {
+	start time.Time
+	mu    sync.Mutex
+	t     Timing
+}
+
+func (r *requestTrace) connectDone(_, _ string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil && r.t.Connect == 0 {
+		r.t.Connect = time.Since(r.start)
+	}
+}
+
+func (r *requestTrace) gotFirstResponseByte() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.t.TTFB = time.Since(r.start)
+}
+
+func (r *requestTrace) timing() Timing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.t
+}
+
+// measureThroughput downloads up to ThroughputSize bytes through the proxy, a download cut
+// short by the timeout still yields the speed observed so far.
+func (c *DefaultChecker) measureThroughput(ctx context.Context, schema string, proxy Spec) Throughput {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.ThroughputURL, nil)
+	if err != nil {
+		return 0
+	}
+
+	client, closeClient, err := c.openClient(ctx, schema, proxy)
+	if err != nil {
+		return 0
 	}
+	defer closeClient()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Target, nil)
+	release, err := c.limits.acquire(ctx, proxy.Host)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return 0
 	}
+	defer release()
+
+	start := time.Now()
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		slog.Debug("throughput measurement failed", slog.Any("proxy", proxy), slog.String("error", err.Error()))
+		return 0
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("non-200 response: %d", resp.StatusCode)
+		return 0
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	n, _ := io.Copy(io.Discard, io.LimitReader(resp.Body, c.ThroughputSize))
+
+	return Throughput(float64(n) / time.Since(start).Seconds())
+}
+
+// openClient returns a client sending its requests through proxy. The client holds one of
+// the MAX_CONNS connection slots until closeClient closes its connections.
+func (c *DefaultChecker) openClient(ctx context.Context, schema string, proxy Spec) (client *http.Client, closeClient func(), err error) {
+	release, err := c.limits.openConn(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, err
 	}
 
-	if strings.Contains(strings.TrimSpace(string(body)), c.realIP) {
-		return fmt.Errorf("proxy IP mismatch: %s", proxy)
+	client = c.client(schema, proxy)
+
+	return client, func() {
+		client.CloseIdleConnections()
+		release()
+	}, nil
+}
+
+// client builds a client for one proxy, its transport must not be shared: connections to
+// the judge through a SOCKS4 proxy are pooled by the judge address alone. DialTimeout
+// bounds connecting to the proxy, Timeout the whole request.
+func (c *DefaultChecker) client(schema string, proxy Spec) *http.Client {
+	dialer := &net.Dialer{Timeout: c.DialTimeout}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     c.tlsConfig,
+		MaxIdleConnsPerHost: 1,
+	}
+
+	switch schema {
+	case "socks4", "socks4a":
+		socks := newSocks4Dialer(proxy.Address(), proxy.Username, schema == "socks4a")
+		socks.dialer.Timeout = c.DialTimeout
+		transport.DialContext = socks.DialContext
+	case "connect":
+		// Requesting an HTTPS judge makes the transport open a CONNECT tunnel through
+		// the proxy and complete the TLS handshake with the judge itself.
+		transport.Proxy = http.ProxyURL(proxy.URL("http"))
+	default:
+		// An https proxy is reached over TLS, its certificate is verified like the judge's.
+		transport.Proxy = http.ProxyURL(proxy.URL(schema))
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   c.Timeout,
+	}
+}
+
+func (c *DefaultChecker) CheckJudge() error {
+	if c.tlsErr != nil {
+		return fmt.Errorf("failed to load TLS_CA: %w", c.tlsErr)
+	}
+
+	_, err := c.loadRealIPs()
+	if errors.Is(err, ErrJudgeHeaders) {
+		return fmt.Errorf("judge %s: %w, run one with the judge command", c.Target, ErrJudgeHeaders)
+	} else if err != nil {
+		return fmt.Errorf("failed to get real IP from judge %s: %w", c.Target, err)
 	}
 
 	return nil
 }
 
-func (c *DefaultChecker) getRealIP() (string, error) {
-	resp, err := http.Get(c.Target)
+// loadRealIPs looks up our own addresses once, they are what a leaking proxy reveals.
+func (c *DefaultChecker) loadRealIPs() ([]string, error) {
+	c.once.Do(func() {
+		c.realIPs, c.realIPErr = c.getRealIPs()
+	})
+
+	return c.realIPs, c.realIPErr
+}
+
+// getRealIPs asks the judge for our address over IPv4 and IPv6, a dual-stack host may
+// leak either of them. Only one of the two address families has to work.
+func (c *DefaultChecker) getRealIPs() ([]string, error) {
+	var (
+		ips  []string
+		errs []error
+	)
+
+	for _, network := range []string{"tcp4", "tcp6"} {
+		ip, err := c.getRealIP(network)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", network, err))
+			continue
+		}
+
+		if !isRealIP(ip, ips) {
+			ips = append(ips, ip)
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return ips, nil
+}
+
+func (c *DefaultChecker) getRealIP(network string) (string, error) {
+	dialer := &net.Dialer{Timeout: c.Timeout}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			TLSClientConfig: c.tlsConfig,
+		},
+		Timeout: c.Timeout,
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(c.Target)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
@@ -189,12 +574,58 @@ func (c *DefaultChecker) getRealIP() (string, error) {
 		return "", fmt.Errorf("non-200 response: %d", resp.StatusCode)
 	}
 
+	judgement, err := c.readJudgement(resp)
+	if err != nil {
+		return "", err
+	}
+
+	return judgement.IP, nil
+}
+
+// readJudgement decodes the JSON document served by the built-in judge and falls back
+// to a plain text body holding only the IP, as served by checkip.amazonaws.com.
+func (c *DefaultChecker) readJudgement(resp *http.Response) (JudgeResponse, error) {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return JudgeResponse{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/json" {
+		if c.Anonymity {
+			return JudgeResponse{}, ErrJudgeHeaders
+		}
+
+		return JudgeResponse{IP: strings.TrimSpace(string(body))}, nil
+	}
+
+	var judgement JudgeResponse
+	if err = json.Unmarshal(body, &judgement); err != nil {
+		return judgement, fmt.Errorf("failed to decode judge response: %w", err)
 	}
 
-	return strings.TrimSpace(string(body)), nil
+	return judgement, nil
+}
+
+// isRealIP reports whether ip is one of realIPs. Addresses are compared by value since
+// IPv6 has several textual forms, anything that does not parse is matched as text.
+func isRealIP(ip string, realIPs []string) bool {
+	parsed := net.ParseIP(ip)
+
+	for _, realIP := range realIPs {
+		if parsed != nil && parsed.Equal(net.ParseIP(realIP)) || parsed == nil && strings.Contains(ip, realIP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type attempt struct {
+	schema    string
+	tries     uint
+	judgement JudgeResponse
+	timing    Timing
+	err       error
 }
 
 func errToStr(err error) string {
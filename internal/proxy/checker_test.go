@@ -2,14 +2,59 @@ package proxy
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"proxy-checker/internal/config"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
+func specOf(line string) Spec {
+	spec, _ := ParseSpec(line)
+	return spec
+}
+
+// TestCheckOne_Timeout covers a proxy that accepts connections and never answers, run with
+// -race it catches trace hooks that still run after the request has failed.
+func TestCheckOne_Timeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	judge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("111.111.111.112"))
+	}))
+	defer judge.Close()
+
+	checker := NewChecker(config.ProxyChecker{API: judge.URL, Timeout: 200 * time.Millisecond, Concurrency: 1})
+
+	res, _ := checker.CheckOne(context.Background(), specOf(listener.Addr().String()))
+	if res.OK() || res.Category != CategoryTimeout {
+		t.Errorf("expected a timed out proxy, got %+v", res)
+	}
+}
+
 func TestDoRequest_IPMismatch(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -29,7 +74,9 @@ func TestDoRequest_IPMismatch(t *testing.T) {
 	checker := NewChecker(cfg)
 
 	checker.(*DefaultChecker).Target = server.URL
-	err := checker.(*DefaultChecker).doRequest(context.Background(), "http", proxyAddress)
+	spec, _ := ParseSpec(proxyAddress)
+	c := checker.(*DefaultChecker)
+	_, _, err := c.doRequest(context.Background(), c.client("http", spec), "http", spec)
 
 	if err == nil || !strings.Contains(err.Error(), "proxy IP mismatch") {
 		t.Fatalf("expected IP mismatch error, got %v", err)
@@ -60,13 +107,22 @@ func TestCheckOne_ValidProxy(t *testing.T) {
 	}
 	checker := NewChecker(cfg)
 
-	proxy, err := checker.CheckOne(context.Background(), proxyAddress)
+	res, err := checker.CheckOne(context.Background(), specOf(proxyAddress))
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	if proxy != proxyAddress {
-		t.Fatalf("expected proxy '%s', got %v", proxyAddress, proxy)
+	if res.Proxy.String() != proxyAddress {
+		t.Fatalf("expected proxy '%s', got %v", proxyAddress, res.Proxy)
+	}
+	if len(res.Protocols) != 1 || res.Protocols[0] != "http" {
+		t.Fatalf("expected protocols [http], got %v", res.Protocols)
+	}
+	if res.ExitIP != "111.111.111.111" {
+		t.Fatalf("expected exit IP 111.111.111.111, got %s", res.ExitIP)
+	}
+	if timing := res.Latency["http"]; timing.Connect <= 0 || timing.TTFB < timing.Connect || timing.Total < timing.TTFB {
+		t.Fatalf("expected ordered positive http timings, got %+v", timing)
 	}
 }
 
@@ -78,11 +134,14 @@ func TestCheckOne_InvalidProxy(t *testing.T) {
 	}
 	checker := NewChecker(cfg)
 
-	_, err := checker.CheckOne(context.Background(), "invalid_proxy")
+	res, err := checker.CheckOne(context.Background(), specOf("invalid_proxy"))
 
 	if err == nil || !strings.Contains(err.Error(), "invalid proxy url") {
 		t.Fatalf("expected invalid proxy URL error, got %v", err)
 	}
+	if res.OK() || res.Category != CategoryInvalid {
+		t.Fatalf("expected failed result with category %q, got %+v", CategoryInvalid, res)
+	}
 }
 
 func TestAwaitCheck(t *testing.T) {
@@ -109,10 +168,10 @@ func TestAwaitCheck(t *testing.T) {
 	}
 	checker := NewChecker(cfg)
 
-	proxiesCh := make(chan string, 3)
-	proxiesCh <- proxyAddress
-	proxiesCh <- "111.111.111.111:8080"
-	proxiesCh <- "invalid_proxy"
+	proxiesCh := make(chan Spec, 3)
+	proxiesCh <- specOf(proxyAddress)
+	proxiesCh <- specOf("111.111.111.111:8080")
+	proxiesCh <- specOf("invalid_proxy")
 	close(proxiesCh)
 
 	results, err := checker.AwaitCheck(context.Background(), proxiesCh)
@@ -121,8 +180,501 @@ func TestAwaitCheck(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	expectedResults := []string{proxyAddress}
-	if len(results) != len(expectedResults) || results[0] != expectedResults[0] {
-		t.Fatalf("expected results %v, got %v", expectedResults, results)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %v", results)
+	}
+
+	var alive []string
+	for _, res := range results {
+		if res.OK() {
+			alive = append(alive, res.Proxy.String())
+		} else if res.Error == "" || res.Category == CategoryNone {
+			t.Fatalf("expected failure details for %s, got %+v", res.Proxy, res)
+		}
+	}
+
+	if len(alive) != 1 || alive[0] != proxyAddress {
+		t.Fatalf("expected alive proxies %v, got %v", []string{proxyAddress}, alive)
+	}
+}
+
+func TestCheckOne_KnownScheme(t *testing.T) {
+	var requests atomic.Int32
+
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer proxyServer.Close()
+
+	checker := NewChecker(config.ProxyChecker{
+		API:         proxyServer.URL,
+		Timeout:     time.Second,
+		Concurrency: 1,
+		Protocols:   []string{"socks4", "socks5"},
+	}).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	res, err := checker.CheckOne(context.Background(), specOf("http://"+proxyServer.Listener.Addr().String()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(res.Protocols) != 1 || res.Protocols[0] != "http" {
+		t.Errorf("expected protocols [http], got %v", res.Protocols)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected the proxy to be probed once, got %d requests", n)
+	}
+}
+
+func TestCheckOne_ConnectTunnel(t *testing.T) {
+	judge := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer judge.Close()
+
+	plainJudge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer plainJudge.Close()
+
+	// The proxy only supports CONNECT tunnels and refuses plain forwarding.
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go io.Copy(target, conn)
+		io.Copy(conn, target)
+	}))
+	defer proxyServer.Close()
+
+	// The judge serves a self-signed certificate, as one in a lab would.
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: judge.Certificate().Raw}), 0o600); err != nil {
+		t.Fatalf("failed to write CA: %v", err)
+	}
+
+	cfg := config.ProxyChecker{
+		API:         plainJudge.URL,
+		TLSAPI:      judge.URL,
+		TLSCA:       caFile,
+		Timeout:     time.Second,
+		Concurrency: 1,
+		Protocols:   []string{"http", "connect"},
+	}
+	checker := NewChecker(cfg).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	res, err := checker.CheckOne(context.Background(), specOf(proxyServer.Listener.Addr().String()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(res.Protocols) != 1 || res.Protocols[0] != "connect" {
+		t.Fatalf("expected protocols [connect], got %v", res.Protocols)
+	}
+}
+
+func TestCheckOne_HTTPSProxy(t *testing.T) {
+	judge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer judge.Close()
+
+	// The proxy only accepts TLS connections and forwards plain requests.
+	proxyServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := http.Get(r.URL.String())
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	defer proxyServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: proxyServer.Certificate().Raw}), 0o600); err != nil {
+		t.Fatalf("failed to write CA: %v", err)
+	}
+
+	cfg := config.ProxyChecker{
+		API:         judge.URL,
+		TLSCA:       caFile,
+		Timeout:     time.Second,
+		Concurrency: 1,
+		Protocols:   []string{"http", "https"},
+	}
+	checker := NewChecker(cfg).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	res, err := checker.CheckOne(context.Background(), specOf("https://"+proxyServer.Listener.Addr().String()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(res.Protocols) != 1 || res.Protocols[0] != "https" {
+		t.Fatalf("expected protocols [https], got %v", res.Protocols)
+	}
+}
+
+func TestNewChecker_TLSCA(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("failed to write CA: %v", err)
+	}
+
+	if err := NewChecker(config.ProxyChecker{TLSCA: caFile}).CheckJudge(); err == nil {
+		t.Errorf("expected an invalid TLS_CA to fail the judge check")
+	}
+}
+
+func TestCheckOne_AuthenticatedProxy(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")) {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer proxyServer.Close()
+
+	cfg := config.ProxyChecker{
+		API:         proxyServer.URL,
+		Timeout:     time.Second,
+		Concurrency: 1,
+		Protocols:   []string{"http"},
+	}
+	checker := NewChecker(cfg).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	addr := proxyServer.Listener.Addr().String()
+
+	if _, err := checker.CheckOne(context.Background(), specOf(addr)); err == nil {
+		t.Fatalf("expected proxy without credentials to fail")
+	}
+
+	res, err := checker.CheckOne(context.Background(), specOf("user:pass@"+addr))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if res.Proxy.String() != "user:pass@"+addr {
+		t.Fatalf("expected credentials to be kept, got %s", res.Proxy)
+	}
+}
+
+func TestCheckOne_Anonymity(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ip":"111.111.111.111","headers":{"Via":["1.1 squid"],"X-Forwarded-For":["111.111.111.112"]}}`))
+	}))
+	defer proxyServer.Close()
+
+	cfg := config.ProxyChecker{
+		API:         proxyServer.URL,
+		Timeout:     time.Second,
+		Concurrency: 1,
+		Protocols:   []string{"http"},
+		Anonymity:   true,
+	}
+	checker := NewChecker(cfg).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	res, err := checker.CheckOne(context.Background(), specOf(proxyServer.Listener.Addr().String()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if res.ExitIP != "111.111.111.111" {
+		t.Errorf("expected exit IP 111.111.111.111, got %s", res.ExitIP)
+	}
+
+	if res.Anonymity != Transparent {
+		t.Errorf("expected transparent proxy, got %q", res.Anonymity)
+	}
+}
+
+func TestCheckOne_JSONJudge(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"ip":"111.111.111.111","headers":{},"received_at":"2024-07-01T12:00:00Z","duration":1000}`))
+	}))
+	defer proxyServer.Close()
+
+	cfg := config.ProxyChecker{
+		API:         proxyServer.URL,
+		Timeout:     time.Second,
+		Concurrency: 1,
+		Protocols:   []string{"http"},
+	}
+	checker := NewChecker(cfg).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	res, err := checker.CheckOne(context.Background(), specOf(proxyServer.Listener.Addr().String()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if res.ExitIP != "111.111.111.111" {
+		t.Errorf("expected exit IP 111.111.111.111, got %s", res.ExitIP)
+	}
+}
+
+func TestGetRealIP_AnonymityRequiresJSONJudge(t *testing.T) {
+	judge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("111.111.111.112"))
+	}))
+	defer judge.Close()
+
+	checker := NewChecker(config.ProxyChecker{API: judge.URL, Anonymity: true}).(*DefaultChecker)
+
+	if _, err := checker.getRealIP("tcp4"); !errors.Is(err, ErrJudgeHeaders) {
+		t.Fatalf("expected judge headers error, got %v", err)
+	}
+
+	checker.Anonymity = false
+
+	ip, err := checker.getRealIP("tcp4")
+	if err != nil || ip != "111.111.111.112" {
+		t.Fatalf("expected real IP 111.111.111.112, got %q, %v", ip, err)
+	}
+}
+
+func TestGetRealIPs_IPv6(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+
+	judge := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		w.Write([]byte(host))
+	}))
+	judge.Listener = listener
+	judge.Start()
+	defer judge.Close()
+
+	checker := NewChecker(config.ProxyChecker{API: judge.URL, Timeout: time.Second}).(*DefaultChecker)
+
+	ips, err := checker.getRealIPs()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(ips) != 1 || ips[0] != "::1" {
+		t.Errorf("expected real IPs [::1], got %v", ips)
+	}
+}
+
+func TestIsRealIP(t *testing.T) {
+	realIPs := []string{"203.0.113.7", "2001:db8::1"}
+
+	for ip, expected := range map[string]bool{
+		"203.0.113.7":             true,
+		"2001:0db8:0:0:0:0:0:1":   true,
+		"203.0.113.70":            false,
+		"2001:db8::2":             false,
+		"client 203.0.113.7 seen": true,
+	} {
+		if isRealIP(ip, realIPs) != expected {
+			t.Errorf("isRealIP(%q) = %v, expected %v", ip, !expected, expected)
+		}
+	}
+}
+
+func TestCheck_Resolve(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer proxyServer.Close()
+
+	_, port, _ := net.SplitHostPort(proxyServer.Listener.Addr().String())
+
+	checker := NewChecker(config.ProxyChecker{
+		API:         proxyServer.URL,
+		Timeout:     time.Second,
+		Concurrency: 1,
+		Protocols:   []string{"http"},
+		Resolve:     true,
+	}).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	proxiesCh := make(chan Spec, 1)
+	proxiesCh <- specOf("localhost:" + port)
+	close(proxiesCh)
+
+	results, err := checker.AwaitCheck(context.Background(), proxiesCh)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var alive []string
+	for _, res := range results {
+		if net.ParseIP(res.Proxy.Host) == nil {
+			t.Errorf("expected resolved address, got %s", res.Proxy.Host)
+		}
+
+		if res.OK() {
+			alive = append(alive, res.Proxy.String())
+		}
+	}
+
+	if len(alive) != 1 || alive[0] != "127.0.0.1:"+port {
+		t.Errorf("expected 127.0.0.1:%s to be alive, got %v", port, alive)
+	}
+}
+
+func TestCheckOne_Throughput(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/payload" {
+			w.Write(make([]byte, 64<<10))
+			return
+		}
+
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer proxyServer.Close()
+
+	cfg := config.ProxyChecker{
+		API:            proxyServer.URL,
+		ThroughputURL:  proxyServer.URL + "/payload",
+		ThroughputSize: 32 << 10,
+		Timeout:        time.Second,
+		Concurrency:    1,
+		Protocols:      []string{"http"},
+	}
+	checker := NewChecker(cfg).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	res, err := checker.CheckOne(context.Background(), specOf(proxyServer.Listener.Addr().String()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if res.Throughput <= 0 {
+		t.Errorf("expected positive throughput, got %v", res.Throughput)
+	}
+}
+
+func TestCheckOne_Retry(t *testing.T) {
+	var requests atomic.Int32
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer proxyServer.Close()
+
+	cfg := config.ProxyChecker{
+		API:           proxyServer.URL,
+		Timeout:       time.Second,
+		Concurrency:   1,
+		Protocols:     []string{"http"},
+		RetryAttempts: 3,
+		RetryBackoff:  time.Millisecond,
+		RetryOn:       []string{"bad_status"},
+	}
+	checker := NewChecker(cfg).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	res, err := checker.CheckOne(context.Background(), specOf(proxyServer.Listener.Addr().String()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if res.Attempts != 2 {
+		t.Errorf("expected proxy to pass on attempt 2, got %d", res.Attempts)
+	}
+}
+
+func TestCheckOne_ClosesConnections(t *testing.T) {
+	var open atomic.Int32
+
+	proxyServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("111.111.111.111"))
+	}))
+	proxyServer.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			open.Add(1)
+		case http.StateClosed, http.StateHijacked:
+			open.Add(-1)
+		}
+	}
+	proxyServer.Start()
+	defer proxyServer.Close()
+
+	_, port, _ := net.SplitHostPort(proxyServer.Listener.Addr().String())
+
+	checker := NewChecker(config.ProxyChecker{
+		API:            proxyServer.URL,
+		Timeout:        time.Second,
+		Protocols:      []string{"http"},
+		ThroughputURL:  proxyServer.URL + "/payload",
+		ThroughputSize: 1024,
+		MaxConns:       1,
+	}).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	for i := 0; i < 3; i++ {
+		if _, err := checker.CheckOne(context.Background(), specOf("127.0.0.1:"+port)); err != nil {
+			t.Fatalf("expected proxy to be alive, got %v", err)
+		}
+	}
+
+	// The server notices the closed connections shortly after the client.
+	deadline := time.Now().Add(time.Second)
+	for open.Load() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := open.Load(); n != 0 {
+		t.Errorf("expected all connections to be closed, %d still open", n)
+	}
+}
+
+func TestNewChecker_DialTimeout(t *testing.T) {
+	tests := []struct {
+		dial, timeout, expected time.Duration
+	}{
+		{dial: time.Second, timeout: 3 * time.Second, expected: time.Second},
+		{dial: 5 * time.Second, timeout: 3 * time.Second, expected: 3 * time.Second},
+		{dial: 0, timeout: 3 * time.Second, expected: 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		c := NewChecker(config.ProxyChecker{DialTimeout: tt.dial, Timeout: tt.timeout}).(*DefaultChecker)
+		if c.DialTimeout != tt.expected {
+			t.Errorf("dial timeout %s with timeout %s: expected %s, got %s", tt.dial, tt.timeout, tt.expected, c.DialTimeout)
+		}
 	}
 }
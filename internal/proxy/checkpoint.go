@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Checkpoint records every checked input line along with its result, so that an interrupted
+// run can be resumed without checking those lines again. The file is JSON Lines and written
+// line by line, a line cut short by a crash is dropped on resume.
+type Checkpoint struct {
+	filename string
+	file     *os.File
+	enc      *json.Encoder
+	done     map[string]struct{}
+	results  []Result
+}
+
+type checkpointEntry struct {
+	Input  string `json:"input"`
+	Result Result `json:"result"`
+}
+
+// UnmarshalJSON keeps the results of invalid input lines, which Spec refuses to decode.
+func (e *checkpointEntry) UnmarshalJSON(data []byte) error {
+	var entry struct {
+		Input  string `json:"input"`
+		Result struct {
+			Proxy string `json:"proxy"`
+			*Result
+		} `json:"result"`
+	}
+	entry.Result.Result = &e.Result
+
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+
+	e.Input = entry.Input
+	e.Result.Proxy, _ = ParseSpec(entry.Result.Proxy)
+	e.Result.Proxy.Raw = entry.Input
+
+	return nil
+}
+
+// OpenCheckpoint starts a checkpoint file, with resume set the entries of an existing file
+// are loaded and new entries are appended to it.
+func OpenCheckpoint(filename string, resume bool) (*Checkpoint, error) {
+	filename, err := expandPath(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Checkpoint{filename: filename, done: make(map[string]struct{})}
+
+	flag := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if resume {
+		flag = os.O_RDWR | os.O_CREATE
+	}
+
+	file, err := os.OpenFile(filename, flag, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+
+	if resume {
+		if err = c.load(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read checkpoint %s: %w", filename, err)
+		}
+	}
+
+	c.file = file
+	c.enc = json.NewEncoder(file)
+
+	return c, nil
+}
+
+// load reads the entries of file and leaves the file positioned after the last complete one.
+func (c *Checkpoint) load(file *os.File) error {
+	reader := bufio.NewReader(file)
+
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				slog.Warn("dropping incomplete checkpoint entry", slog.String("checkpoint", c.filename))
+			}
+			break
+		} else if err != nil {
+			return err
+		}
+
+		var entry checkpointEntry
+		if err = json.Unmarshal(line, &entry); err != nil {
+			slog.Warn("dropping incomplete checkpoint entry", slog.String("checkpoint", c.filename))
+			break
+		}
+
+		offset += int64(len(line))
+		c.done[entry.Input] = struct{}{}
+		c.results = append(c.results, entry.Result)
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+
+	_, err := file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// Resumed reports whether entries of an earlier run were loaded.
+func (c *Checkpoint) Resumed() bool {
+	return len(c.done) > 0
+}
+
+// Results returns the results loaded from an earlier run.
+func (c *Checkpoint) Results() []Result {
+	return c.results
+}
+
+// Skip drops the input lines that were checked by an earlier run.
+func (c *Checkpoint) Skip(in <-chan Spec) <-chan Spec {
+	out := make(chan Spec)
+
+	go func() {
+		defer close(out)
+
+		for spec := range in {
+			if _, ok := c.done[spec.Raw]; !ok {
+				out <- spec
+			}
+		}
+	}()
+
+	return out
+}
+
+// Record adds the result of an input line to the checkpoint.
+func (c *Checkpoint) Record(res Result) error {
+	return c.enc.Encode(checkpointEntry{Input: res.Proxy.Raw, Result: res})
+}
+
+func (c *Checkpoint) Close() error {
+	return c.file.Close()
+}
+
+// Remove closes and deletes the checkpoint once the run is complete.
+func (c *Checkpoint) Remove() error {
+	return errors.Join(c.Close(), os.Remove(c.filename))
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint_Resume(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ok.txt.checkpoint")
+
+	c, err := OpenCheckpoint(filename, false)
+	if err != nil {
+		t.Fatalf("failed to open checkpoint: %v", err)
+	}
+
+	invalid, _ := ParseSpec("not a proxy")
+	for _, res := range []Result{
+		{Proxy: specOf("127.0.0.1:8080"), Protocols: []string{"http"}},
+		{Proxy: invalid, Category: CategoryInvalid},
+	} {
+		if err = c.Record(res); err != nil {
+			t.Fatalf("failed to record result: %v", err)
+		}
+	}
+	c.Close()
+
+	// A crash in the middle of a write leaves an incomplete line behind.
+	file, _ := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+	file.WriteString(`{"input":"10.0.0.1:80","res`)
+	file.Close()
+
+	c, err = OpenCheckpoint(filename, true)
+	if err != nil {
+		t.Fatalf("failed to resume checkpoint: %v", err)
+	}
+
+	if !c.Resumed() || len(c.Results()) != 2 || !c.Results()[0].OK() || c.Results()[1].Category != CategoryInvalid {
+		t.Fatalf("unexpected resumed results %+v", c.Results())
+	}
+
+	in := make(chan Spec, 3)
+	in <- specOf("127.0.0.1:8080")
+	in <- invalid
+	in <- specOf("10.0.0.1:80")
+	close(in)
+
+	var left []string
+	for spec := range c.Skip(in) {
+		left = append(left, spec.String())
+	}
+
+	if len(left) != 1 || left[0] != "10.0.0.1:80" {
+		t.Errorf("expected only 10.0.0.1:80 to be left, got %v", left)
+	}
+
+	if err = c.Record(Result{Proxy: specOf("10.0.0.1:80"), Category: CategoryTimeout}); err != nil {
+		t.Fatalf("failed to record result: %v", err)
+	}
+
+	if err = c.Remove(); err != nil {
+		t.Fatalf("failed to remove checkpoint: %v", err)
+	}
+
+	if _, err = os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("expected checkpoint to be removed, got %v", err)
+	}
+}
+
+func TestCheckpoint_Fresh(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ok.txt.checkpoint")
+	os.WriteFile(filename, []byte(`{"input":"127.0.0.1:8080","result":{"proxy":"127.0.0.1:8080"}}`+"\n"), 0o644)
+
+	c, err := OpenCheckpoint(filename, false)
+	if err != nil {
+		t.Fatalf("failed to open checkpoint: %v", err)
+	}
+	defer c.Close()
+
+	if c.Resumed() {
+		t.Errorf("expected a run without -resume to start over")
+	}
+}
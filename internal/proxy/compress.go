@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// compression returns the compression extension of filename, if any.
+func compression(filename string) string {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".gz", ".zst":
+		return ext
+	default:
+		return ""
+	}
+}
+
+// decompress streams the content of a file compressed according to its extension.
+func decompress(filename string, r io.Reader) (io.ReadCloser, error) {
+	switch compression(filename) {
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".zst":
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+// compress streams into a file compressed according to its extension, closing the returned
+// writer flushes the compressor but leaves w open.
+func compress(filename string, w io.Writer) (io.WriteCloser, error) {
+	switch compression(filename) {
+	case ".gz":
+		return gzip.NewWriter(w), nil
+	case ".zst":
+		return zstd.NewWriter(w)
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
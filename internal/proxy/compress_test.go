@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompressedRoundTrip(t *testing.T) {
+	proxies := []string{"127.0.0.1:8080", "socks5://1.2.3.4:1080"}
+
+	tests := []struct {
+		name  string
+		magic []byte
+	}{
+		{"proxies.txt.gz", []byte{0x1f, 0x8b}},
+		{"proxies.txt.zst", []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), tt.name)
+
+			resultsCh := make(chan Result, len(proxies))
+			for _, p := range proxies {
+				resultsCh <- Result{Proxy: specOf(p), Protocols: []string{"http"}}
+			}
+			close(resultsCh)
+
+			if err := NewFileWriter(filename, WriteOptions{}).Write(context.Background(), resultsCh); err != nil {
+				t.Fatalf("failed to write proxies: %v", err)
+			}
+
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+
+			if !bytes.HasPrefix(data, tt.magic) {
+				t.Fatalf("expected compressed output, got %q", data)
+			}
+
+			if read := readAll(t, NewFileReader(filename, FormatAuto)); !slices.Equal(read, proxies) {
+				t.Errorf("expected %v, got %v", proxies, read)
+			}
+		})
+	}
+}
+
+func TestCompressedCSV(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "proxies.csv.gz")
+
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte("ip,port,country\n1.2.3.4,8080,US\n"))
+	gz.Close()
+	file.Close()
+
+	expected := []string{"1.2.3.4:8080#country=US"}
+	if read := readAll(t, NewFileReader(filename, FormatAuto)); !slices.Equal(read, expected) {
+		t.Errorf("expected %v, got %v", expected, read)
+	}
+}
+
+func TestCompressedInvalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "proxies.txt.zst")
+	if err := os.WriteFile(filename, []byte("not really zstd"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if err := NewFileReader(filename, FormatAuto).Read(context.Background(), make(chan Spec, 1)); err == nil {
+		t.Errorf("expected error reading a corrupt zstd file")
+	}
+}
@@ -0,0 +1,296 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// resultFields are written along with the proxy by the checker, they are dropped when its
+// output is read back rather than kept as tags.
+var resultFields = map[string]bool{
+	"source":     true,
+	"protocols":  true,
+	"latency":    true,
+	"latency_ms": true,
+	"throughput": true,
+	"attempts":   true,
+	"exit_ip":    true,
+	"anonymity":  true,
+	"error":      true,
+	"category":   true,
+	"checked_at": true,
+}
+
+// fieldAliases maps the column names found in published proxy lists to the parts of a Spec,
+// any other column is kept as a tag.
+var fieldAliases = map[string]string{
+	"host":     "host",
+	"ip":       "host",
+	"address":  "host",
+	"addr":     "host",
+	"proxy":    "host",
+	"port":     "port",
+	"protocol": "scheme",
+	"scheme":   "scheme",
+	"type":     "scheme",
+	"user":     "username",
+	"username": "username",
+	"login":    "username",
+	"pass":     "password",
+	"password": "password",
+}
+
+// decode reads proxies from r in the given format and sends them to proxiesCh labelled with
+// their source, an entry that is not a proxy is sent as an invalid spec.
+func decode(ctx context.Context, r io.Reader, format Format, source string, proxiesCh chan<- Spec) error {
+	send := func(spec Spec) error {
+		spec.Source = source
+
+		select {
+		case proxiesCh <- spec:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	switch format {
+	case FormatCSV:
+		return decodeCSV(r, send)
+	case FormatJSON:
+		return decodeJSON(r, send)
+	case FormatJSONL:
+		return decodeJSONL(r, send)
+	default:
+		return decodeText(r, send)
+	}
+}
+
+func decodeText(r io.Reader, send func(Spec) error) error {
+	reader := bufio.NewReader(r)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimSpace(line); line != "" {
+			spec, _ := ParseSpec(line)
+			if sendErr := send(spec); sendErr != nil {
+				return sendErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func decodeCSV(r io.Reader, send func(Spec) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	if !hasHostField(header) {
+		return fmt.Errorf("csv header has no host column: %s", strings.Join(header, ","))
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		fields := make(map[string]string, len(header))
+		for i, value := range record {
+			if i < len(header) {
+				fields[header[i]] = value
+			}
+		}
+
+		if err = send(specFromFields(fields, strings.Join(record, ","))); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeJSON(r io.Reader, send func(Spec) error) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	if t, err := dec.Token(); err != nil {
+		return fmt.Errorf("failed to read json: %w", err)
+	} else if t != json.Delim('[') {
+		return fmt.Errorf("json input must be an array, got %v", t)
+	}
+
+	for dec.More() {
+		var entry json.RawMessage
+		if err := dec.Decode(&entry); err != nil {
+			return fmt.Errorf("failed to read json: %w", err)
+		}
+
+		if err := send(specFromJSON(entry)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeJSONL(r io.Reader, send func(Spec) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := send(specFromJSON(line)); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// specFromJSON accepts both a bare proxy string and an object with proxy fields.
+func specFromJSON(entry []byte) Spec {
+	var line string
+	if err := json.Unmarshal(entry, &line); err == nil {
+		spec, _ := ParseSpec(line)
+		return spec
+	}
+
+	var object map[string]any
+
+	dec := json.NewDecoder(bytes.NewReader(entry))
+	dec.UseNumber()
+
+	if err := dec.Decode(&object); err != nil {
+		spec, _ := ParseSpec(string(entry))
+		return spec
+	}
+
+	fields := make(map[string]string, len(object))
+	for k, v := range object {
+		if resultFields[strings.ToLower(k)] {
+			continue
+		}
+
+		switch v := v.(type) {
+		case nil:
+		case map[string]any:
+			// Nested tags, e.g. {"host": "1.2.3.4", "port": 80, "tags": {"country": "US"}}.
+			for tk, tv := range v {
+				fields[tk] = fmt.Sprint(tv)
+			}
+		default:
+			fields[k] = fmt.Sprint(v)
+		}
+	}
+
+	return specFromFields(fields, string(entry))
+}
+
+// specFromFields builds a spec from named fields, raw is the entry as it was read and is
+// reported when the fields do not make a proxy.
+func specFromFields(fields map[string]string, raw string) Spec {
+	var (
+		parts = make(map[string]string, len(fieldAliases))
+		tags  = make(map[string]string)
+	)
+
+	for k, v := range fields {
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		if v == "" || resultFields[k] {
+			continue
+		}
+
+		if part, ok := fieldAliases[k]; ok {
+			parts[part] = v
+		} else {
+			tags[k] = v
+		}
+	}
+
+	invalid := Spec{Raw: raw, err: fmt.Errorf("%w: %s", ErrInvalidProxy, raw)}
+
+	var (
+		spec Spec
+		err  error
+	)
+
+	if parts["port"] == "" {
+		// The host column holds the whole proxy, e.g. 1.2.3.4:8080 or socks5://1.2.3.4:1080
+		// as the JSON formats write it, the other columns are laid over it.
+		if spec, err = ParseSpec(parts["host"]); err != nil {
+			return invalid
+		}
+	} else {
+		addr := parts["host"]
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+			addr = "[" + addr + "]"
+		}
+		addr += ":" + parts["port"]
+
+		// Only the address is parsed, the credentials are taken as they are.
+		if loc := addrPattern.FindStringIndex(addr); loc == nil || loc[0] != 0 || loc[1] != len(addr) {
+			return invalid
+		}
+
+		if spec, err = ParseSpec(addr); err != nil {
+			return invalid
+		}
+	}
+
+	if parts["scheme"] != "" {
+		if !schemePattern.MatchString(parts["scheme"]) {
+			return invalid
+		}
+		spec.Scheme = strings.ToLower(parts["scheme"])
+	}
+
+	if parts["username"] != "" {
+		spec.Username, spec.Password = parts["username"], parts["password"]
+	}
+	spec.Raw = raw
+
+	if len(tags) > 0 && spec.Tags == nil {
+		spec.Tags = make(map[string]string, len(tags))
+	}
+
+	for k, v := range tags {
+		spec.Tags[k] = v
+	}
+
+	return spec
+}
+
+func hasHostField(header []string) bool {
+	for _, name := range header {
+		if fieldAliases[strings.ToLower(strings.TrimSpace(name))] == "host" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"sync/atomic"
+)
+
+// Dedup drops proxies that were already seen during a run. Proxies are told apart by Key, so
+// the same address listed with another scheme is still checked.
+type Dedup struct {
+	seen    map[string]struct{}
+	dropped atomic.Int64
+}
+
+func NewDedup() *Dedup {
+	return &Dedup{seen: make(map[string]struct{})}
+}
+
+// Filter passes on the first occurrence of every proxy, invalid lines are always passed on
+// so that they are reported.
+func (d *Dedup) Filter(in <-chan Spec) <-chan Spec {
+	out := make(chan Spec)
+
+	go func() {
+		defer close(out)
+
+		for spec := range in {
+			if spec.Err() == nil {
+				key := spec.Key()
+				if _, ok := d.seen[key]; ok {
+					d.dropped.Add(1)
+					continue
+				}
+
+				d.seen[key] = struct{}{}
+			}
+
+			out <- spec
+		}
+	}()
+
+	return out
+}
+
+// Dropped returns the number of duplicates filtered out so far.
+func (d *Dedup) Dropped() int {
+	return int(d.dropped.Load())
+}
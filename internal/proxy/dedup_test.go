@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDedup_Filter(t *testing.T) {
+	lines := []string{
+		"1.2.3.4:8080",
+		"user:pass@1.2.3.4:08080#country=US",
+		"http://1.2.3.4:8080",
+		"[2001:db8::1]:1080",
+		"[2001:0db8:0::1]:1080",
+		"invalid_proxy",
+		"invalid_proxy",
+	}
+
+	in := make(chan Spec, len(lines))
+	for _, line := range lines {
+		in <- specOf(line)
+	}
+	close(in)
+
+	dedup := NewDedup()
+
+	var out []string
+	for spec := range dedup.Filter(in) {
+		out = append(out, spec.Raw)
+	}
+
+	expected := []string{"1.2.3.4:8080", "http://1.2.3.4:8080", "[2001:db8::1]:1080", "invalid_proxy", "invalid_proxy"}
+	if !slices.Equal(out, expected) {
+		t.Errorf("expected %v, got %v", expected, out)
+	}
+
+	if n := dedup.Dropped(); n != 2 {
+		t.Errorf("expected 2 duplicates dropped, got %d", n)
+	}
+}
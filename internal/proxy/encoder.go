@@ -0,0 +1,242 @@
+package proxy
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultColumns are written by the CSV format unless other columns are selected.
+var DefaultColumns = []string{"proxy", "protocols", "latency_ms", "throughput", "anonymity", "exit_ip"}
+
+// defaultFailureColumns are written by the CSV format of a failure report.
+var defaultFailureColumns = []string{"input", "source", "category", "error"}
+
+// columns extract the CSV fields of a result, any other column name is looked up in the tags
+// of the proxy, e.g. country.
+var columns = map[string]func(Result) string{
+	"proxy":    func(r Result) string { return r.Proxy.String() },
+	"input":    func(r Result) string { return r.Proxy.Raw },
+	"scheme":   func(r Result) string { return r.Proxy.Scheme },
+	"host":     func(r Result) string { return r.Proxy.Host },
+	"port":     func(r Result) string { return r.Proxy.Port },
+	"username": func(r Result) string { return r.Proxy.Username },
+	"password": func(r Result) string { return r.Proxy.Password },
+	"protocols": func(r Result) string {
+		return strings.Join(r.Protocols, " ")
+	},
+	"latency_ms": func(r Result) string {
+		if !r.OK() {
+			return ""
+		}
+		return strconv.FormatInt(r.BestLatency().Milliseconds(), 10)
+	},
+	"throughput": func(r Result) string {
+		if r.Throughput == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(float64(r.Throughput), 'f', 0, 64)
+	},
+	"anonymity": func(r Result) string { return string(r.Anonymity) },
+	"exit_ip":   func(r Result) string { return r.ExitIP },
+	"attempts": func(r Result) string {
+		return strconv.FormatUint(uint64(r.Attempts), 10)
+	},
+	"source":   func(r Result) string { return r.Source },
+	"category": func(r Result) string { return string(r.Category) },
+	"error":    func(r Result) string { return r.Error },
+	"checked_at": func(r Result) string {
+		return r.CheckedAt.Format(time.RFC3339)
+	},
+}
+
+// encoder writes results in an output format, Close completes the document without closing
+// the underlying writer.
+type encoder interface {
+	Encode(res Result) error
+	Close() error
+}
+
+// failure is the record of a failure report, it names the input line as it was read so
+// that it can be found in the upstream list.
+type failure struct {
+	Input     string    `json:"input"`
+	Source    string    `json:"source,omitempty"`
+	Category  Category  `json:"category"`
+	Error     string    `json:"error"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+func newFailure(res Result) any {
+	return failure{
+		Input:     res.Proxy.Raw,
+		Source:    res.Source,
+		Category:  res.Category,
+		Error:     res.Error,
+		CheckedAt: res.CheckedAt,
+	}
+}
+
+func newEncoder(w io.Writer, opts WriteOptions) (encoder, error) {
+	record := func(res Result) any { return res }
+	if opts.Failed {
+		record = newFailure
+	}
+
+	switch opts.Format {
+	case FormatAuto, FormatText:
+		if opts.Failed {
+			return &failureTextEncoder{w: w}, nil
+		}
+		return &textEncoder{w: w}, nil
+	case FormatJSONL:
+		return &jsonlEncoder{enc: json.NewEncoder(w), record: record}, nil
+	case FormatJSON:
+		return &jsonEncoder{w: w, record: record}, nil
+	case FormatCSV:
+		cols := opts.Columns
+		if len(cols) == 0 && opts.Failed {
+			cols = defaultFailureColumns
+		} else if len(cols) == 0 {
+			cols = DefaultColumns
+		}
+		return &csvEncoder{w: csv.NewWriter(w), columns: cols}, nil
+	}
+
+	if format := opts.Format; format.isTemplate() {
+		tmpl, err := loadTemplate(format)
+		if err != nil {
+			return nil, err
+		}
+
+		return &templateEncoder{w: w, tmpl: tmpl}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported output format: %s", opts.Format)
+}
+
+type textEncoder struct {
+	w io.Writer
+}
+
+func (e *textEncoder) Encode(res Result) error {
+	_, err := fmt.Fprintln(e.w, res.Proxy)
+	return err
+}
+
+func (e *textEncoder) Close() error {
+	return nil
+}
+
+// failureTextEncoder writes the input line, the error category and the error separated by tabs.
+// Line breaks and tabs of joined errors are replaced to keep one failure per line.
+type failureTextEncoder struct {
+	w io.Writer
+}
+
+var failureTextReplacer = strings.NewReplacer("\r\n", "; ", "\n", "; ", "\r", "; ", "\t", " ")
+
+func (e *failureTextEncoder) Encode(res Result) error {
+	_, err := fmt.Fprintf(e.w, "%s\t%s\t%s\n", res.Proxy.Raw, res.Category, failureTextReplacer.Replace(res.Error))
+	return err
+}
+
+func (e *failureTextEncoder) Close() error {
+	return nil
+}
+
+type jsonlEncoder struct {
+	enc    *json.Encoder
+	record func(Result) any
+}
+
+func (e *jsonlEncoder) Encode(res Result) error {
+	return e.enc.Encode(e.record(res))
+}
+
+func (e *jsonlEncoder) Close() error {
+	return nil
+}
+
+// jsonEncoder streams a JSON array, the array is only complete once Close is called.
+type jsonEncoder struct {
+	w      io.Writer
+	record func(Result) any
+	count  int
+}
+
+func (e *jsonEncoder) Encode(res Result) error {
+	data, err := json.Marshal(e.record(res))
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n  "
+	if e.count == 0 {
+		sep = "[\n  "
+	}
+	e.count++
+
+	_, err = fmt.Fprintf(e.w, "%s%s", sep, data)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type csvEncoder struct {
+	w         *csv.Writer
+	columns   []string
+	wroteHead bool
+}
+
+func (e *csvEncoder) Encode(res Result) error {
+	if !e.wroteHead {
+		e.wroteHead = true
+		if err := e.w.Write(e.columns); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(e.columns))
+	for i, col := range e.columns {
+		if field, ok := columns[col]; ok {
+			record[i] = field(res)
+		} else {
+			record[i] = res.Proxy.Tags[col]
+		}
+	}
+
+	if err := e.w.Write(record); err != nil {
+		return err
+	}
+
+	// Results trickle in over minutes, flushing keeps the output readable while checking.
+	e.w.Flush()
+
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	if !e.wroteHead {
+		e.wroteHead = true
+		if err := e.w.Write(e.columns); err != nil {
+			return err
+		}
+	}
+
+	e.w.Flush()
+
+	return e.w.Error()
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encodeAll(t *testing.T, format Format, cols []string, results ...Result) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	enc, err := newEncoder(&buf, WriteOptions{Format: format, Columns: cols})
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v", err)
+	}
+
+	for _, res := range results {
+		if err = enc.Encode(res); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+	}
+
+	if err = enc.Close(); err != nil {
+		t.Fatalf("failed to close encoder: %v", err)
+	}
+
+	return buf.String()
+}
+
+func TestEncoder(t *testing.T) {
+	results := []Result{
+		{
+			Proxy:      specOf("socks5://1.2.3.4:1080#country=US"),
+			Protocols:  []string{"socks5"},
+			Latency:    map[string]Timing{"socks5": {Total: 250 * time.Millisecond}},
+			Throughput: 2048,
+			ExitIP:     "1.2.3.4",
+		},
+		{
+			Proxy:     specOf("5.6.7.8:8080"),
+			Protocols: []string{"http", "socks5"},
+			Latency:   map[string]Timing{"http": {Total: 120 * time.Millisecond}, "socks5": {Total: 90 * time.Millisecond}},
+			Anonymity: Elite,
+		},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		expected := "socks5://1.2.3.4:1080#country=US\n5.6.7.8:8080\n"
+		if out := encodeAll(t, FormatText, nil, results...); out != expected {
+			t.Errorf("expected %q, got %q", expected, out)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		expected := "proxy,protocols,latency_ms,throughput,anonymity,exit_ip\n" +
+			"socks5://1.2.3.4:1080#country=US,socks5,250,2048,,1.2.3.4\n" +
+			"5.6.7.8:8080,http socks5,90,,elite,\n"
+		if out := encodeAll(t, FormatCSV, nil, results...); out != expected {
+			t.Errorf("expected %q, got %q", expected, out)
+		}
+
+		expected = "host,port,country\n1.2.3.4,1080,US\n5.6.7.8,8080,\n"
+		if out := encodeAll(t, FormatCSV, []string{"host", "port", "country"}, results...); out != expected {
+			t.Errorf("expected %q, got %q", expected, out)
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(encodeAll(t, FormatJSONL, nil, results...)), "\n")
+		if len(lines) != len(results) {
+			t.Fatalf("expected %d lines, got %d", len(results), len(lines))
+		}
+
+		var decoded Result
+		if err := json.Unmarshal([]byte(lines[1]), &decoded); err != nil {
+			t.Fatalf("failed to decode line: %v", err)
+		}
+
+		if decoded.Proxy.String() != "5.6.7.8:8080" || decoded.Anonymity != Elite {
+			t.Errorf("unexpected result %+v", decoded)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var decoded []Result
+		if err := json.Unmarshal([]byte(encodeAll(t, FormatJSON, nil, results...)), &decoded); err != nil {
+			t.Fatalf("failed to decode array: %v", err)
+		}
+
+		if len(decoded) != len(results) || decoded[0].Proxy.String() != results[0].Proxy.String() {
+			t.Errorf("unexpected results %+v", decoded)
+		}
+
+		if out := encodeAll(t, FormatJSON, nil); out != "[]\n" {
+			t.Errorf("expected empty array, got %q", out)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+var (
+	ErrInvalidProxy     = errors.New("invalid proxy url")
+	ErrDNS              = errors.New("dns lookup failed")
+	ErrConnRefused      = errors.New("connection refused")
+	ErrTimeout          = errors.New("timeout")
+	ErrTLS              = errors.New("tls handshake failed")
+	ErrAuthRequired     = errors.New("proxy authentication required")
+	ErrBadStatus        = errors.New("non-200 response")
+	ErrIPLeak           = errors.New("proxy IP mismatch")
+	ErrProtocolMismatch = errors.New("protocol mismatch")
+	ErrUnreachable      = errors.New("proxy unreachable")
+)
+
+// ErrJudgeHeaders is returned when the anonymity check is enabled and the judge answers with
+// plain text rather than the JSON document of the built-in judge.
+var ErrJudgeHeaders = errors.New("judge does not echo request headers, anonymity check requires a JSON judge")
+
+type Category string
+
+const (
+	CategoryNone             Category = ""
+	CategoryInvalid          Category = "invalid"
+	CategoryDNS              Category = "dns"
+	CategoryRefused          Category = "refused"
+	CategoryTimeout          Category = "timeout"
+	CategoryTLS              Category = "tls"
+	CategoryAuthRequired     Category = "auth_required"
+	CategoryBadStatus        Category = "bad_status"
+	CategoryIPLeak           Category = "ip_leak"
+	CategoryProtocolMismatch Category = "protocol_mismatch"
+	CategoryUnreachable      Category = "unreachable"
+)
+
+// categories is ordered from the most to the least telling failure: a proxy that leaked
+// our IP over one protocol says more about it than the timeouts of the other protocols.
+var categories = []struct {
+	kind     error
+	category Category
+}{
+	{ErrInvalidProxy, CategoryInvalid},
+	{ErrIPLeak, CategoryIPLeak},
+	{ErrAuthRequired, CategoryAuthRequired},
+	{ErrBadStatus, CategoryBadStatus},
+	{ErrTLS, CategoryTLS},
+	{ErrProtocolMismatch, CategoryProtocolMismatch},
+	{ErrTimeout, CategoryTimeout},
+	{ErrConnRefused, CategoryRefused},
+	{ErrDNS, CategoryDNS},
+	{ErrUnreachable, CategoryUnreachable},
+}
+
+func ParseCategory(s string) (Category, error) {
+	for _, c := range categories {
+		if string(c.category) == s {
+			return c.category, nil
+		}
+	}
+
+	return CategoryNone, fmt.Errorf("unknown error category: %s", s)
+}
+
+func Categorize(err error) Category {
+	if err == nil {
+		return CategoryNone
+	}
+
+	return categories[rank(err)].category
+}
+
+// rank returns the position of the category of err in categories.
+func rank(err error) int {
+	err = classifyError(err)
+
+	for i, c := range categories {
+		if errors.Is(err, c.kind) {
+			return i
+		}
+	}
+
+	return len(categories) - 1
+}
+
+// checkError keeps the original error message while making the sentinel of its
+// category available to errors.Is.
+type checkError struct {
+	kind error
+	err  error
+}
+
+func (e *checkError) Error() string {
+	return e.err.Error()
+}
+
+func (e *checkError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, c := range categories {
+		if errors.Is(err, c.kind) {
+			return err
+		}
+	}
+
+	return &checkError{kind: kindOf(err), err: err}
+}
+
+func kindOf(err error) error {
+	var (
+		dnsErr         *net.DNSError
+		netErr         net.Error
+		recordErr      tls.RecordHeaderError
+		alertErr       tls.AlertError
+		verifyErr      *tls.CertificateVerificationError
+		unknownAuthErr x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+	)
+
+	// net/http reports failed CONNECT and SOCKS5 handshakes as plain strings.
+	msg := err.Error()
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return ErrDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrConnRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &verifyErr),
+		errors.As(err, &unknownAuthErr), errors.As(err, &hostnameErr):
+		return ErrTLS
+	case strings.Contains(msg, http.StatusText(http.StatusProxyAuthRequired)),
+		strings.Contains(msg, "username/password authentication failed"),
+		strings.Contains(msg, "no acceptable authentication methods"):
+		return ErrAuthRequired
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		strings.Contains(msg, "malformed HTTP"),
+		strings.Contains(msg, "unexpected protocol version"):
+		return ErrProtocolMismatch
+	default:
+		return ErrUnreachable
+	}
+}
+
+// mostTelling picks the error explaining best why a proxy failed across all protocols.
+func mostTelling(errs []error) error {
+	var best error
+
+	for _, err := range errs {
+		if err != nil && (best == nil || rank(err) < rank(best)) {
+			best = err
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	closedAddr := l.Addr().String()
+	l.Close()
+
+	_, refusedErr := net.DialTimeout("tcp", closedAddr, time.Second)
+
+	tests := []struct {
+		name     string
+		err      error
+		kind     error
+		category Category
+	}{
+		{"dns", &net.DNSError{Err: "no such host", Name: "proxy.invalid", IsNotFound: true}, ErrDNS, CategoryDNS},
+		{"refused", fmt.Errorf("request failed: %w", refusedErr), ErrConnRefused, CategoryRefused},
+		{"timeout", fmt.Errorf("request failed: %w", context.DeadlineExceeded), ErrTimeout, CategoryTimeout},
+		{"tls", fmt.Errorf("request failed: %w", x509.UnknownAuthorityError{}), ErrTLS, CategoryTLS},
+		{"connect auth", errors.New("proxyconnect tcp: Proxy Authentication Required"), ErrAuthRequired, CategoryAuthRequired},
+		{"bad status", fmt.Errorf("%w: 502", ErrBadStatus), ErrBadStatus, CategoryBadStatus},
+		{"ip leak", fmt.Errorf("%w: 1.2.3.4:80", ErrIPLeak), ErrIPLeak, CategoryIPLeak},
+		{"eof", fmt.Errorf("request failed: %w", io.EOF), ErrProtocolMismatch, CategoryProtocolMismatch},
+		{"unknown", errors.New("something else"), ErrUnreachable, CategoryUnreachable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("expected %v to be %v", err, tt.kind)
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected original error to be kept in %v", err)
+			}
+
+			if err.Error() != tt.err.Error() {
+				t.Errorf("expected message %q, got %q", tt.err.Error(), err.Error())
+			}
+
+			if c := Categorize(tt.err); c != tt.category {
+				t.Errorf("expected category %q, got %q", tt.category, c)
+			}
+		})
+	}
+}
+
+func TestMostTelling(t *testing.T) {
+	leak := fmt.Errorf("%w: 1.2.3.4:80", ErrIPLeak)
+	timeout := fmt.Errorf("request failed: %w", context.DeadlineExceeded)
+	eof := fmt.Errorf("request failed: %w", io.EOF)
+
+	if err := mostTelling([]error{timeout, leak, eof}); err != leak {
+		t.Errorf("expected IP leak, got %v", err)
+	}
+
+	if err := mostTelling([]error{timeout, eof}); err != eof {
+		t.Errorf("expected protocol mismatch, got %v", err)
+	}
+
+	if err := mostTelling(nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	if c, err := ParseCategory("timeout"); err != nil || c != CategoryTimeout {
+		t.Errorf("expected timeout, got %q, %v", c, err)
+	}
+
+	if _, err := ParseCategory("boom"); err == nil || !strings.Contains(err.Error(), "unknown error category") {
+		t.Errorf("expected unknown category error, got %v", err)
+	}
+}
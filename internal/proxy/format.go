@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatAuto  Format = ""
+	FormatText  Format = "text"
+	FormatCSV   Format = "csv"
+	FormatJSON  Format = "json"
+	FormatJSONL Format = "jsonl"
+
+	// Template formats only apply to the output.
+	FormatProxychains Format = "proxychains"
+	FormatPAC         Format = "pac"
+	FormatURLs        Format = "urls"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatAuto, FormatText, FormatCSV, FormatJSON, FormatJSONL:
+		return f, nil
+	case "ndjson":
+		return FormatJSONL, nil
+	}
+
+	if strings.HasPrefix(s, templatePrefix) {
+		// Template errors are reported now rather than after all proxies were checked.
+		if _, err := loadTemplate(Format(s)); err != nil {
+			return FormatAuto, err
+		}
+
+		return Format(s), nil
+	}
+
+	if f := Format(strings.ToLower(s)); f.isTemplate() {
+		return f, nil
+	}
+
+	return FormatAuto, fmt.Errorf("unknown format: %s", s)
+}
+
+// ParseInputFormat is like ParseFormat but rejects the formats that can only be written.
+func ParseInputFormat(s string) (Format, error) {
+	f, err := ParseFormat(s)
+	if err == nil && f.isTemplate() {
+		return FormatAuto, fmt.Errorf("%s is an output format", s)
+	}
+
+	return f, err
+}
+
+// formatOf picks the format of a file by its extension, anything unknown is read as text.
+// A compression extension is skipped, list.csv.gz is a CSV file.
+func formatOf(filename string) Format {
+	if ext := compression(filename); ext != "" {
+		filename = filename[:len(filename)-len(ext)]
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	case ".pac":
+		return FormatPAC
+	default:
+		return FormatText
+	}
+}
@@ -0,0 +1,250 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// listTimeout bounds waiting for a list server, for the response headers and then for every
+// read of the body, so a server stalling mid-list does not hang the run.
+const listTimeout = 30 * time.Second
+
+// HTTPReader fetches a published proxy list. The list is cached along with its ETag and
+// Last-Modified headers so that an unchanged list is read from disk on the next run.
+type HTTPReader struct {
+	url      string
+	path     string
+	format   Format
+	cacheDir string
+	client   *http.Client
+	timeout  time.Duration
+}
+
+type cacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func isURL(in string) bool {
+	return strings.HasPrefix(in, "http://") || strings.HasPrefix(in, "https://")
+}
+
+func NewHTTPReader(rawURL string, format Format) *HTTPReader {
+	var path string
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+
+	if format == FormatAuto {
+		format = formatOf(path)
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		slog.Warn("proxy list caching disabled", slog.String("error", err.Error()))
+	} else {
+		cacheDir = filepath.Join(cacheDir, "proxy-checker", "lists")
+	}
+
+	return &HTTPReader{
+		url:      rawURL,
+		path:     path,
+		format:   format,
+		cacheDir: cacheDir,
+		client:   newListClient(listTimeout),
+		timeout:  listTimeout,
+	}
+}
+
+func newListClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+
+	return &http.Client{Transport: transport}
+}
+
+func (r *HTTPReader) Read(ctx context.Context, proxiesCh chan<- Spec) error {
+	defer close(proxiesCh)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	cached, hasCache := r.loadCache()
+	if hasCache {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", r.url, err)
+	}
+	resp.Body = &idleReader{r: resp.Body, timeout: r.timeout, cancel: cancel}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && hasCache:
+		slog.Debug("proxy list not modified, reading cached copy", slog.String("url", r.url))
+		return r.readCache(ctx, proxiesCh)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("failed to fetch %s: %d", r.url, resp.StatusCode)
+	}
+
+	entry := cacheEntry{URL: r.url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return r.decode(ctx, resp.Body, proxiesCh)
+	}
+
+	tmp, err := r.createTemp()
+	if err != nil {
+		slog.Warn("failed to cache proxy list", slog.String("url", r.url), slog.String("error", err.Error()))
+		return r.decode(ctx, resp.Body, proxiesCh)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// The list is streamed into the checker while it is written to the cache.
+	body := io.TeeReader(resp.Body, tmp)
+	if err = r.decode(ctx, body, proxiesCh); err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(io.Discard, body); err == nil {
+		err = r.storeCache(tmp, entry)
+	}
+
+	if err != nil {
+		slog.Warn("failed to cache proxy list", slog.String("url", r.url), slog.String("error", err.Error()))
+	}
+
+	return nil
+}
+
+func (r *HTTPReader) cachePath() string {
+	sum := sha256.Sum256([]byte(r.url))
+	return filepath.Join(r.cacheDir, hex.EncodeToString(sum[:]))
+}
+
+func (r *HTTPReader) loadCache() (cacheEntry, bool) {
+	var entry cacheEntry
+
+	if r.cacheDir == "" {
+		return entry, false
+	}
+
+	data, err := os.ReadFile(r.cachePath() + ".json")
+	if err != nil {
+		return entry, false
+	}
+
+	if err = json.Unmarshal(data, &entry); err != nil || entry.URL != r.url {
+		return entry, false
+	}
+
+	if _, err = os.Stat(r.cachePath()); err != nil {
+		return entry, false
+	}
+
+	return entry, true
+}
+
+func (r *HTTPReader) readCache(ctx context.Context, proxiesCh chan<- Spec) error {
+	file, err := os.Open(r.cachePath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return r.decode(ctx, file, proxiesCh)
+}
+
+// decode reads the list as it was served, a compressed list is cached compressed.
+func (r *HTTPReader) decode(ctx context.Context, body io.Reader, proxiesCh chan<- Spec) error {
+	content, err := decompress(r.path, body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", r.url, err)
+	}
+	defer content.Close()
+
+	return decode(ctx, content, r.format, r.url, proxiesCh)
+}
+
+func (r *HTTPReader) createTemp() (*os.File, error) {
+	if r.cacheDir == "" {
+		return nil, errors.New("no cache directory")
+	}
+
+	if err := os.MkdirAll(r.cacheDir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return os.CreateTemp(r.cacheDir, "download-*")
+}
+
+func (r *HTTPReader) storeCache(tmp *os.File, entry cacheEntry) error {
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), r.cachePath()); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.cachePath()+".json", data, 0o600)
+}
+
+// idleReader cancels a download when a single read waits longer than timeout. Only the time
+// spent in Read counts, a list that is read slowly because the checker is busy is fine.
+type idleReader struct {
+	r       io.ReadCloser
+	timeout time.Duration
+	cancel  context.CancelFunc
+	stalled atomic.Bool
+}
+
+func (i *idleReader) Read(p []byte) (int, error) {
+	timer := time.AfterFunc(i.timeout, func() {
+		i.stalled.Store(true)
+		i.cancel()
+	})
+	defer timer.Stop()
+
+	n, err := i.r.Read(p)
+	if err != nil && i.stalled.Load() {
+		err = fmt.Errorf("no data received for %s", i.timeout)
+	}
+
+	return n, err
+}
+
+func (i *idleReader) Close() error {
+	return i.r.Close()
+}
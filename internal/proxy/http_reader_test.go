@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, reader Reader) []string {
+	t.Helper()
+
+	proxiesCh := make(chan Spec)
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- reader.Read(context.Background(), proxiesCh)
+	}()
+
+	var proxies []string
+	for spec := range proxiesCh {
+		proxies = append(proxies, spec.String())
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("failed to read proxies: %v", err)
+	}
+
+	return proxies
+}
+
+func TestHTTPReader_Read(t *testing.T) {
+	var downloads atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		downloads.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("127.0.0.1:8080\nsocks5://1.2.3.4:1080\n"))
+	}))
+	defer server.Close()
+
+	expected := []string{"127.0.0.1:8080", "socks5://1.2.3.4:1080"}
+	cacheDir := t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		reader := NewHTTPReader(server.URL+"/proxies.txt", FormatAuto)
+		reader.cacheDir = cacheDir
+
+		if proxies := readAll(t, reader); !slices.Equal(proxies, expected) {
+			t.Errorf("run %d: expected %v, got %v", i, expected, proxies)
+		}
+	}
+
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("expected the list to be downloaded once, got %d", n)
+	}
+}
+
+func TestHTTPReader_Format(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["127.0.0.1:8080"]`))
+	}))
+	defer server.Close()
+
+	reader := NewHTTPReader(server.URL+"/proxies.json?token=secret", FormatAuto)
+	reader.cacheDir = t.TempDir()
+
+	if proxies := readAll(t, reader); !slices.Equal(proxies, []string{"127.0.0.1:8080"}) {
+		t.Errorf("expected [127.0.0.1:8080], got %v", proxies)
+	}
+}
+
+func TestHTTPReader_BadStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	reader := NewHTTPReader(server.URL, FormatAuto)
+	reader.cacheDir = t.TempDir()
+
+	proxiesCh := make(chan Spec, 1)
+	if err := reader.Read(context.Background(), proxiesCh); err == nil {
+		t.Errorf("expected error for missing list")
+	}
+}
+
+func TestHTTPReader_Stalled(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/body.txt" {
+			w.Write([]byte("127.0.0.1:8080\n"))
+			w.(http.Flusher).Flush()
+		}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	for _, path := range []string{"/headers.txt", "/body.txt"} {
+		reader := NewHTTPReader(server.URL+path, FormatAuto)
+		reader.cacheDir = t.TempDir()
+		reader.client = newListClient(100 * time.Millisecond)
+		reader.timeout = 100 * time.Millisecond
+
+		proxiesCh := make(chan Spec, 1)
+		done := make(chan error, 1)
+		go func() { done <- reader.Read(context.Background(), proxiesCh) }()
+
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Errorf("%s: expected error for a stalled list server", path)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: read hangs on a stalled list server", path)
+		}
+	}
+}
+
+func TestMultiReader_Read(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("10.0.0.1:3128\n"))
+	}))
+	defer server.Close()
+
+	reader := NewMultiReader(NewFileReader("testdata/read_proxies.txt", FormatAuto), NewReader(server.URL, FormatAuto))
+
+	expected := []string{"10.0.0.1:3128", "127.0.0.1:8080", "192.168.0.1:3128"}
+	proxies := readAll(t, reader)
+	slices.Sort(proxies)
+
+	if !slices.Equal(proxies, expected) {
+		t.Errorf("expected %v, got %v", expected, proxies)
+	}
+}
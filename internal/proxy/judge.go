@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Anonymity string
+
+const (
+	AnonymityUnknown Anonymity = ""
+	Transparent      Anonymity = "transparent"
+	Anonymous        Anonymity = "anonymous"
+	Elite            Anonymity = "elite"
+)
+
+// proxyHeaders are the request headers proxies use to announce themselves or the client behind them.
+var proxyHeaders = []string{
+	"Via",
+	"Forwarded",
+	"X-Forwarded-For",
+	"X-Forwarded-Host",
+	"X-Forwarded-Proto",
+	"X-Real-Ip",
+	"X-Client-Ip",
+	"Client-Ip",
+	"X-Proxy-Id",
+	"Proxy-Connection",
+}
+
+type JudgeResponse struct {
+	IP         string        `json:"ip"`
+	Headers    http.Header   `json:"headers,omitempty"`
+	TLS        *JudgeTLS     `json:"tls,omitempty"`
+	ReceivedAt time.Time     `json:"received_at"`
+	Duration   time.Duration `json:"duration"`
+}
+
+type JudgeTLS struct {
+	Version            string `json:"version"`
+	CipherSuite        string `json:"cipher_suite"`
+	ServerName         string `json:"server_name,omitempty"`
+	NegotiatedProtocol string `json:"negotiated_protocol,omitempty"`
+}
+
+func NewJudgeTLS(state *tls.ConnectionState) *JudgeTLS {
+	if state == nil {
+		return nil
+	}
+
+	return &JudgeTLS{
+		Version:            tls.VersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+	}
+}
+
+func ParseAnonymity(s string) (Anonymity, error) {
+	switch a := Anonymity(strings.ToLower(s)); a {
+	case AnonymityUnknown, Transparent, Anonymous, Elite:
+		return a, nil
+	default:
+		return AnonymityUnknown, fmt.Errorf("unknown anonymity level: %s", s)
+	}
+}
+
+func (a Anonymity) level() int {
+	switch a {
+	case Transparent:
+		return 1
+	case Anonymous:
+		return 2
+	case Elite:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// AtLeast reports whether a is as anonymous as min, any level satisfies AnonymityUnknown.
+func (a Anonymity) AtLeast(min Anonymity) bool {
+	return a.level() >= min.level()
+}
+
+// Anonymity grades the proxy from the headers the judge received, realIPs are the addresses
+// a transparent proxy would forward.
+func (j JudgeResponse) Anonymity(realIPs ...string) Anonymity {
+	anonymity := Elite
+
+	for _, name := range proxyHeaders {
+		values := j.Headers.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		anonymity = Anonymous
+
+		for _, v := range values {
+			for _, realIP := range realIPs {
+				if realIP != "" && strings.Contains(v, realIP) {
+					return Transparent
+				}
+			}
+		}
+	}
+
+	return anonymity
+}
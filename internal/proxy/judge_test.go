@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestJudgeResponse_Anonymity(t *testing.T) {
+	realIP := "203.0.113.7"
+
+	tests := []struct {
+		name     string
+		headers  http.Header
+		expected Anonymity
+	}{
+		{"no proxy headers", http.Header{"User-Agent": {"Go-http-client/1.1"}}, Elite},
+		{"via header", http.Header{"Via": {"1.1 squid"}}, Anonymous},
+		{"forwarded for another address", http.Header{"X-Forwarded-For": {"10.0.0.1"}}, Anonymous},
+		{"forwarded for real address", http.Header{"Via": {"1.1 squid"}, "X-Forwarded-For": {"203.0.113.7, 10.0.0.1"}}, Transparent},
+		{"forwarded header", http.Header{"Forwarded": {"for=203.0.113.7"}}, Transparent},
+		{"real ip header", http.Header{"X-Real-Ip": {"203.0.113.7"}}, Transparent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a := (JudgeResponse{IP: "198.51.100.1", Headers: tt.headers}).Anonymity(realIP); a != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, a)
+			}
+		})
+	}
+}
+
+func TestAnonymity_AtLeast(t *testing.T) {
+	if !Elite.AtLeast(Anonymous) || !Anonymous.AtLeast(Anonymous) || Transparent.AtLeast(Anonymous) {
+		t.Errorf("unexpected anonymity ordering")
+	}
+
+	if !AnonymityUnknown.AtLeast(AnonymityUnknown) || AnonymityUnknown.AtLeast(Transparent) {
+		t.Errorf("unexpected unknown anonymity ordering")
+	}
+}
+
+func TestParseAnonymity(t *testing.T) {
+	if a, err := ParseAnonymity("Elite"); err != nil || a != Elite {
+		t.Errorf("expected elite, got %q, %v", a, err)
+	}
+
+	if _, err := ParseAnonymity("invisible"); err == nil {
+		t.Errorf("expected error for unknown level")
+	}
+}
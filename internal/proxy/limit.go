@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"net"
+	"proxy-checker/internal/config"
+	"sync"
+)
+
+// limits pace the requests sent through proxies: a global rate of requests per second and
+// caps on the requests in flight to one host and to one subnet, so that a list full of a
+// single provider's addresses is not checked all at once. conns bounds the connections,
+// and with them the file descriptors, open at once. A nil limits allows everything.
+type limits struct {
+	rate    *rate.Limiter
+	hosts   *keyedSemaphore
+	subnets *keyedSemaphore
+	conns   chan struct{}
+	prefix4 int
+	prefix6 int
+}
+
+func newLimits(cfg config.ProxyChecker) *limits {
+	if cfg.RateLimit <= 0 && cfg.HostConcurrency == 0 && cfg.SubnetConcurrency == 0 && cfg.MaxConns == 0 {
+		return nil
+	}
+
+	l := &limits{prefix4: cfg.SubnetPrefix, prefix6: cfg.Subnet6Prefix}
+
+	if cfg.RateLimit > 0 {
+		l.rate = rate.NewLimiter(rate.Limit(cfg.RateLimit), 1)
+	}
+
+	if cfg.HostConcurrency > 0 {
+		l.hosts = newKeyedSemaphore(cfg.HostConcurrency)
+	}
+
+	if cfg.SubnetConcurrency > 0 {
+		l.subnets = newKeyedSemaphore(cfg.SubnetConcurrency)
+	}
+
+	if cfg.MaxConns > 0 {
+		l.conns = make(chan struct{}, cfg.MaxConns)
+	}
+
+	return l
+}
+
+// openConn waits for a free connection slot, release gives it back once the connections of
+// its holder are closed.
+func (l *limits) openConn(ctx context.Context) (release func(), err error) {
+	if l == nil || l.conns == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.conns <- struct{}{}:
+		return func() { <-l.conns }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// acquire waits until a request to host may be sent, release has to be called once the
+// request is done.
+func (l *limits) acquire(ctx context.Context, host string) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	var releases []func()
+	release = func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+
+	if l.hosts != nil {
+		r, err := l.hosts.acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+
+	if l.subnets != nil {
+		r, err := l.subnets.acquire(ctx, l.subnet(host))
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+// subnet returns the network of an IP host, a hostname is its own subnet.
+func (l *limits) subnet(host string) string {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(l.prefix4, 32)), Mask: net.CIDRMask(l.prefix4, 32)}).String()
+	}
+
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(l.prefix6, 128)), Mask: net.CIDRMask(l.prefix6, 128)}).String()
+}
+
+// keyedSemaphore allows up to limit holders per key, keys without holders are forgotten.
+type keyedSemaphore struct {
+	limit uint
+	mu    sync.Mutex
+	slots map[string]*semaphoreSlot
+}
+
+type semaphoreSlot struct {
+	ch    chan struct{}
+	users int
+}
+
+func newKeyedSemaphore(limit uint) *keyedSemaphore {
+	return &keyedSemaphore{limit: limit, slots: make(map[string]*semaphoreSlot)}
+}
+
+func (s *keyedSemaphore) acquire(ctx context.Context, key string) (func(), error) {
+	s.mu.Lock()
+	slot, ok := s.slots[key]
+	if !ok {
+		slot = &semaphoreSlot{ch: make(chan struct{}, s.limit)}
+		s.slots[key] = slot
+	}
+	slot.users++
+	s.mu.Unlock()
+
+	select {
+	case slot.ch <- struct{}{}:
+		return func() {
+			<-slot.ch
+			s.leave(key, slot)
+		}, nil
+	case <-ctx.Done():
+		s.leave(key, slot)
+		return nil, ctx.Err()
+	}
+}
+
+func (s *keyedSemaphore) leave(key string, slot *semaphoreSlot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slot.users--; slot.users == 0 {
+		delete(s.slots, key)
+	}
+}
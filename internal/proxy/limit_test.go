@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"proxy-checker/internal/config"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimits_Subnet(t *testing.T) {
+	l := newLimits(config.ProxyChecker{SubnetConcurrency: 1, SubnetPrefix: 24, Subnet6Prefix: 64})
+
+	tests := map[string]string{
+		"1.2.3.4":           "1.2.3.0/24",
+		"1.2.3.200":         "1.2.3.0/24",
+		"2001:db8::1":       "2001:db8::/64",
+		"proxy.example.com": "proxy.example.com",
+	}
+
+	for host, expected := range tests {
+		if subnet := l.subnet(host); subnet != expected {
+			t.Errorf("expected subnet %s for %s, got %s", expected, host, subnet)
+		}
+	}
+}
+
+func TestLimits_Nil(t *testing.T) {
+	var l *limits
+	if l = newLimits(config.ProxyChecker{SubnetPrefix: 24, Subnet6Prefix: 64}); l != nil {
+		t.Fatalf("expected no limits without settings")
+	}
+
+	release, err := l.acquire(context.Background(), "1.2.3.4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release()
+}
+
+func TestLimits_SubnetConcurrency(t *testing.T) {
+	l := newLimits(config.ProxyChecker{SubnetConcurrency: 2, SubnetPrefix: 24, Subnet6Prefix: 64})
+
+	var (
+		wg              sync.WaitGroup
+		inFlight, peak  atomic.Int32
+		otherSubnetDone = make(chan struct{})
+	)
+
+	for _, host := range []string{"1.2.3.1", "1.2.3.2", "1.2.3.3", "1.2.3.4"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release, err := l.acquire(context.Background(), host)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			defer release()
+
+			if n := inFlight.Add(1); n > peak.Load() {
+				peak.Store(n)
+			}
+			time.Sleep(20 * time.Millisecond)
+			inFlight.Add(-1)
+		}()
+	}
+
+	// Another subnet is not held up by the busy one.
+	go func() {
+		release, _ := l.acquire(context.Background(), "5.6.7.8")
+		release()
+		close(otherSubnetDone)
+	}()
+
+	select {
+	case <-otherSubnetDone:
+	case <-time.After(10 * time.Millisecond):
+		t.Errorf("expected another subnet to be checked right away")
+	}
+
+	wg.Wait()
+
+	if p := peak.Load(); p > 2 {
+		t.Errorf("expected at most 2 requests in flight, got %d", p)
+	}
+
+	if len(l.subnets.slots) != 0 {
+		t.Errorf("expected idle subnets to be forgotten, got %v", l.subnets.slots)
+	}
+}
+
+func TestLimits_Canceled(t *testing.T) {
+	l := newLimits(config.ProxyChecker{HostConcurrency: 1})
+
+	release, _ := l.acquire(context.Background(), "1.2.3.4")
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := l.acquire(ctx, "1.2.3.4"); err == nil {
+		t.Errorf("expected error waiting for a busy host")
+	}
+}
+
+func TestLimits_Rate(t *testing.T) {
+	l := newLimits(config.ProxyChecker{RateLimit: 50})
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		release, err := l.acquire(context.Background(), "1.2.3.4")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		release()
+	}
+
+	// The first request passes at once, the other five wait 20ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected requests to be paced to 50/s, took %s", elapsed)
+	}
+}
+
+func TestCheck_HostConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := inFlight.Add(1); n > peak.Load() {
+			peak.Store(n)
+		}
+		defer inFlight.Add(-1)
+
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("111.111.111.111"))
+	}))
+	defer proxyServer.Close()
+
+	_, port, _ := net.SplitHostPort(proxyServer.Listener.Addr().String())
+
+	checker := NewChecker(config.ProxyChecker{
+		API:             proxyServer.URL,
+		Timeout:         time.Second,
+		Concurrency:     4,
+		Protocols:       []string{"http"},
+		HostConcurrency: 1,
+	}).(*DefaultChecker)
+	checker.once.Do(func() { checker.realIPs = []string{"111.111.111.112"} })
+
+	proxiesCh := make(chan Spec, 4)
+	for i := 0; i < 4; i++ {
+		proxiesCh <- specOf("127.0.0.1:" + port)
+	}
+	close(proxiesCh)
+
+	results, err := checker.AwaitCheck(context.Background(), proxiesCh)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, res := range results {
+		if !res.OK() {
+			t.Errorf("expected proxy to be alive, got %s", res.Error)
+		}
+	}
+
+	if p := peak.Load(); p != 1 {
+		t.Errorf("expected one request in flight per host, got %d", p)
+	}
+}
+
+func TestLimits_OpenConn(t *testing.T) {
+	l := newLimits(config.ProxyChecker{MaxConns: 2})
+
+	first, _ := l.openConn(context.Background())
+	second, _ := l.openConn(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := l.openConn(ctx); err == nil {
+		t.Errorf("expected error waiting beyond the connection ceiling")
+	}
+
+	first()
+
+	release, err := l.openConn(context.Background())
+	if err != nil {
+		t.Fatalf("expected a freed slot, got %v", err)
+	}
+
+	release()
+	second()
+}
@@ -3,42 +3,73 @@ package proxy
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
+// Reader parses every non-empty input line into a Spec, lines that are not proxies are passed
+// on as well so that they are reported as invalid.
 type Reader interface {
-	Read(ctx context.Context, proxiesCh chan<- string) error
+	Read(ctx context.Context, proxiesCh chan<- Spec) error
 }
 
 type FileReader struct {
 	filename string
+	format   Format
 }
 
 type StdinReader struct {
+	format Format
 }
 
-func NewReader(in string) Reader {
-	if in == "stdin" {
-		return NewStdinReader()
+// MultiReader reads all its readers concurrently into a single channel.
+type MultiReader struct {
+	readers []Reader
+}
+
+// NewReader reads proxies from the in file, URL or stdin, FormatAuto picks the format of a
+// file or URL by its extension and reads stdin as text.
+func NewReader(in string, format Format) Reader {
+	switch {
+	case in == "stdin":
+		return NewStdinReader(format)
+	case isURL(in):
+		return NewHTTPReader(in, format)
+	default:
+		return NewFileReader(in, format)
+	}
+}
+
+func NewMultiReader(readers ...Reader) Reader {
+	if len(readers) == 1 {
+		return readers[0]
 	}
 
-	return NewFileReader(in)
+	return &MultiReader{readers: readers}
 }
 
-func NewFileReader(filename string) Reader {
-	return &FileReader{filename: filename}
+func NewFileReader(filename string, format Format) Reader {
+	if format == FormatAuto {
+		format = formatOf(filename)
+	}
+
+	return &FileReader{filename: filename, format: format}
 }
 
-func NewStdinReader() *StdinReader {
-	return &StdinReader{}
+func NewStdinReader(format Format) *StdinReader {
+	if format == FormatAuto {
+		format = FormatText
+	}
+
+	return &StdinReader{format: format}
 }
 
-func (r *FileReader) Read(ctx context.Context, proxiesCh chan<- string) error {
+func (r *FileReader) Read(ctx context.Context, proxiesCh chan<- Spec) error {
 	defer close(proxiesCh)
 
 	filename, err := expandPath(r.filename)
@@ -52,32 +83,22 @@ func (r *FileReader) Read(ctx context.Context, proxiesCh chan<- string) error {
 	}
 	defer file.Close()
 
-	var line string
-	reader := bufio.NewReader(file)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-
-		}
-
-		if line, err = reader.ReadString('\n'); err == io.EOF {
-			break
-		}
-
-		proxiesCh <- strings.TrimSpace(line)
+	content, err := decompress(filename, file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", r.filename, err)
 	}
+	defer content.Close()
 
-	return nil
+	return decode(ctx, content, r.format, r.filename, proxiesCh)
 }
 
-func (r *StdinReader) Read(ctx context.Context, proxiesCh chan<- string) error {
+func (r *StdinReader) Read(ctx context.Context, proxiesCh chan<- Spec) error {
 	scanner := bufio.NewScanner(os.Stdin)
 
-	if _, err := fmt.Fprintln(os.Stdout, "Enter proxy address IP:PORT"); err != nil {
-		return err
+	if r.format == FormatText {
+		if _, err := fmt.Fprintln(os.Stdout, "Enter proxy address HOST:PORT"); err != nil {
+			return err
+		}
 	}
 
 	errCh := make(chan error)
@@ -86,6 +107,11 @@ func (r *StdinReader) Read(ctx context.Context, proxiesCh chan<- string) error {
 		defer close(proxiesCh)
 		defer close(errCh)
 
+		if r.format != FormatText {
+			errCh <- decode(ctx, os.Stdin, r.format, "stdin", proxiesCh)
+			return
+		}
+
 		var line string
 		for {
 			if !scanner.Scan() {
@@ -101,7 +127,9 @@ func (r *StdinReader) Read(ctx context.Context, proxiesCh chan<- string) error {
 				continue
 			}
 
-			proxiesCh <- line
+			spec, _ := ParseSpec(line)
+			spec.Source = "stdin"
+			proxiesCh <- spec
 		}
 	}()
 
@@ -113,6 +141,123 @@ func (r *StdinReader) Read(ctx context.Context, proxiesCh chan<- string) error {
 	}
 }
 
+func (r *MultiReader) Read(ctx context.Context, proxiesCh chan<- Spec) error {
+	defer close(proxiesCh)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(r.readers))
+
+	for i, reader := range r.readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ch := make(chan Spec)
+			errCh := make(chan error, 1)
+
+			go func() {
+				errCh <- reader.Read(ctx, ch)
+			}()
+
+			for spec := range ch {
+				select {
+				case proxiesCh <- spec:
+				case <-ctx.Done():
+					errs[i] = ctx.Err()
+					return
+				}
+			}
+
+			errs[i] = <-errCh
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// NewInputReader reads all inputs concurrently. An input is stdin, an http(s) URL, a file,
+// a directory whose files are all read or a glob pattern such as ~/proxies/*.txt.
+func NewInputReader(inputs []string, format Format) (Reader, error) {
+	var readers []Reader
+
+	for _, in := range inputs {
+		if in == "stdin" || isURL(in) {
+			readers = append(readers, NewReader(in, format))
+			continue
+		}
+
+		files, err := expandInput(in)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			readers = append(readers, NewFileReader(file, format))
+		}
+	}
+
+	return NewMultiReader(readers...), nil
+}
+
+// expandInput resolves a glob pattern or a directory into the files it stands for.
+func expandInput(in string) ([]string, error) {
+	path, err := expandPath(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern %s: %w", in, err)
+		}
+
+		var files []string
+		for _, match := range matches {
+			// Unlike a shell, filepath.Glob lets * match hidden files.
+			if strings.HasPrefix(filepath.Base(match), ".") && !strings.HasPrefix(filepath.Base(path), ".") {
+				continue
+			}
+
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				files = append(files, match)
+			}
+		}
+
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no input files match %s", in)
+		}
+
+		return files, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		// A missing file is reported by the reader.
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no input files in %s", in)
+	}
+
+	return files, nil
+}
+
 func expandPath(filename string) (string, error) {
 	if strings.HasPrefix(filename, "~") {
 		usr, err := user.Current()
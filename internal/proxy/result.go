@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+)
+
+type Result struct {
+	Proxy      Spec              `json:"proxy"`
+	Source     string            `json:"source,omitempty"`
+	Protocols  []string          `json:"protocols,omitempty"`
+	Latency    map[string]Timing `json:"latency,omitempty"`
+	Throughput Throughput        `json:"throughput,omitempty"`
+	Attempts   uint              `json:"attempts,omitempty"`
+	ExitIP     string            `json:"exit_ip,omitempty"`
+	Anonymity  Anonymity         `json:"anonymity,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Category   Category          `json:"category,omitempty"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
+
+// Timing holds the durations of a single check, all measured from the start of the request.
+type Timing struct {
+	Connect time.Duration `json:"connect"`
+	TTFB    time.Duration `json:"ttfb"`
+	Total   time.Duration `json:"total"`
+}
+
+// Throughput is a download speed in bytes per second.
+type Throughput float64
+
+func (t Throughput) String() string {
+	switch {
+	case t >= 1<<20:
+		return fmt.Sprintf("%.1f MB/s", t/(1<<20))
+	case t >= 1<<10:
+		return fmt.Sprintf("%.1f KB/s", t/(1<<10))
+	default:
+		return fmt.Sprintf("%.0f B/s", t)
+	}
+}
+
+func (r Result) OK() bool {
+	return len(r.Protocols) > 0
+}
+
+func (r Result) BestLatency() time.Duration {
+	return r.Latency[r.FastestProtocol()].Total
+}
+
+func (r Result) FastestProtocol() string {
+	var fastest string
+	for _, p := range r.Protocols {
+		if fastest == "" || r.Latency[p].Total < r.Latency[fastest].Total {
+			fastest = p
+		}
+	}
+
+	return fastest
+}
+
+func (r Result) failed(err error) (Result, error) {
+	r.Error = err.Error()
+	r.Category = Categorize(err)
+
+	return r, err
+}
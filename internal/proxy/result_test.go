@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResult_FastestProtocol(t *testing.T) {
+	res := Result{
+		Protocols: []string{"http", "socks5"},
+		Latency: map[string]Timing{
+			"http":   {Total: 300 * time.Millisecond},
+			"socks5": {Total: 120 * time.Millisecond},
+		},
+	}
+
+	if p := res.FastestProtocol(); p != "socks5" {
+		t.Errorf("expected socks5, got %s", p)
+	}
+
+	if l := res.BestLatency(); l != 120*time.Millisecond {
+		t.Errorf("expected 120ms, got %s", l)
+	}
+
+	if l := (Result{}).BestLatency(); l != 0 {
+		t.Errorf("expected zero latency for failed result, got %s", l)
+	}
+}
+
+func TestThroughput_String(t *testing.T) {
+	tests := []struct {
+		throughput Throughput
+		expected   string
+	}{
+		{512, "512 B/s"},
+		{1536, "1.5 KB/s"},
+		{3 << 20, "3.0 MB/s"},
+	}
+
+	for _, tt := range tests {
+		if s := tt.throughput.String(); s != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, s)
+		}
+	}
+}
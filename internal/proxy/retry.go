@@ -0,0 +1,21 @@
+package proxy
+
+import (
+	"slices"
+	"time"
+)
+
+type RetryPolicy struct {
+	Attempts  uint
+	Backoff   time.Duration
+	Retryable []Category
+}
+
+func (p RetryPolicy) retry(attempt uint, err error) bool {
+	return err != nil && attempt < p.Attempts && slices.Contains(p.Retryable, Categorize(err))
+}
+
+// delay doubles the backoff after every failed attempt.
+func (p RetryPolicy) delay(attempt uint) time.Duration {
+	return p.Backoff << (attempt - 1)
+}
@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Retry(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, Backoff: 100 * time.Millisecond, Retryable: []Category{CategoryTimeout}}
+	timeout := fmt.Errorf("request failed: %w", context.DeadlineExceeded)
+
+	tests := []struct {
+		name     string
+		attempt  uint
+		err      error
+		expected bool
+	}{
+		{"success", 1, nil, false},
+		{"retryable error", 1, timeout, true},
+		{"last attempt", 3, timeout, false},
+		{"non-retryable error", 1, fmt.Errorf("%w: 502", ErrBadStatus), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if retry := policy.retry(tt.attempt, tt.err); retry != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, retry)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond}
+
+	for attempt, expected := range map[uint]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond} {
+		if d := policy.delay(attempt); d != expected {
+			t.Errorf("expected delay %s after attempt %d, got %s", expected, attempt, d)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	socks4Version = 0x04
+	socks4Connect = 0x01
+	socks4Granted = 0x5a
+)
+
+var errSocks4Rejected = errors.New("socks4 request rejected")
+
+type socks4Dialer struct {
+	proxy  string
+	userID string
+	// remote makes the dialer speak SOCKS4a and leave hostname resolution to the proxy.
+	remote bool
+	dialer net.Dialer
+}
+
+func newSocks4Dialer(proxy, userID string, remote bool) *socks4Dialer {
+	return &socks4Dialer{proxy: proxy, userID: userID, remote: remote}
+}
+
+func (d *socks4Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" {
+		return nil, fmt.Errorf("socks4: network %s is not supported", network)
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("socks4: %w", err)
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("socks4: invalid port %s: %w", portStr, err)
+	}
+
+	req := []byte{socks4Version, socks4Connect, 0, 0}
+	binary.BigEndian.PutUint16(req[2:], uint16(port))
+
+	ip, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if ip == nil {
+		// SOCKS4a marks a remote lookup with the invalid address 0.0.0.x.
+		req = append(req, 0, 0, 0, 1)
+	} else {
+		req = append(req, ip...)
+	}
+
+	req = append(req, d.userID...)
+	req = append(req, 0)
+
+	if ip == nil {
+		req = append(req, host...)
+		req = append(req, 0)
+	}
+
+	conn, err := d.dialer.DialContext(ctx, "tcp", d.proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err = d.handshake(conn, req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+func (d *socks4Dialer) resolve(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4, nil
+		}
+
+		return nil, fmt.Errorf("socks4: IPv6 destination %s is not supported", host)
+	}
+
+	if d.remote {
+		return nil, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+	if err != nil {
+		return nil, fmt.Errorf("socks4: %w", err)
+	}
+
+	return ips[0].To4(), nil
+}
+
+func (d *socks4Dialer) handshake(conn net.Conn, req []byte) error {
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("socks4: failed to write request: %w", err)
+	}
+
+	resp := make([]byte, 8)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return fmt.Errorf("socks4: failed to read response: %w", err)
+	}
+
+	if resp[0] != 0 {
+		return fmt.Errorf("%w: socks4 unexpected reply version %d", ErrProtocolMismatch, resp[0])
+	}
+
+	if resp[1] != socks4Granted {
+		return fmt.Errorf("%w: code %d", errSocks4Rejected, resp[1])
+	}
+
+	return nil
+}
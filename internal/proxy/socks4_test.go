@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"proxy-checker/internal/config"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// startSocks4Server runs a minimal SOCKS4/4a proxy and returns its address.
+func startSocks4Server(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveSocks4(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func serveSocks4(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(r, head); err != nil || head[0] != socks4Version || head[1] != socks4Connect {
+		return
+	}
+
+	if _, err := r.ReadString(0); err != nil {
+		return
+	}
+
+	host := net.IP(head[4:8]).String()
+	if head[4] == 0 && head[5] == 0 && head[6] == 0 && head[7] != 0 {
+		name, err := r.ReadString(0)
+		if err != nil {
+			return
+		}
+		host = name[:len(name)-1]
+	}
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(head[2:4])))))
+	if err != nil {
+		_, _ = conn.Write([]byte{0, 0x5b, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+
+	if _, err = conn.Write([]byte{0, socks4Granted, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+
+	go func() { _, _ = io.Copy(target, r) }()
+	_, _ = io.Copy(conn, target)
+}
+
+func TestSocks4Dialer_DialContext(t *testing.T) {
+	judge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer judge.Close()
+
+	proxyAddr := startSocks4Server(t)
+	_, port, _ := net.SplitHostPort(judge.Listener.Addr().String())
+
+	tests := []struct {
+		name   string
+		target string
+		remote bool
+	}{
+		{"socks4 with IP", "127.0.0.1:" + port, false},
+		{"socks4 with local resolution", "localhost:" + port, false},
+		{"socks4a with remote resolution", "localhost:" + port, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			conn, err := newSocks4Dialer(proxyAddr, "", tt.remote).DialContext(ctx, "tcp", tt.target)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			conn.Close()
+		})
+	}
+}
+
+func TestSocks4Dialer_Rejected(t *testing.T) {
+	proxyAddr := startSocks4Server(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Port 1 on localhost is expected to refuse connections, so the proxy rejects the request.
+	_, err := newSocks4Dialer(proxyAddr, "", false).DialContext(ctx, "tcp", "127.0.0.1:1")
+	if err == nil {
+		t.Fatalf("expected rejection error, got nil")
+	}
+}
+
+func TestCheckOne_Socks4Proxy(t *testing.T) {
+	judge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("111.111.111.111"))
+	}))
+	defer judge.Close()
+
+	proxyAddr := startSocks4Server(t)
+
+	checker := NewChecker(config.ProxyChecker{
+		API:         judge.URL,
+		Timeout:     time.Second,
+		Concurrency: 1,
+	})
+	checker.(*DefaultChecker).once.Do(func() {
+		checker.(*DefaultChecker).realIPs = []string{"111.111.111.112"}
+	})
+
+	res, err := checker.CheckOne(context.Background(), specOf(proxyAddr))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(res.Protocols) != 1 || res.Protocols[0] != "socks4" {
+		t.Fatalf("expected protocols [socks4], got %v", res.Protocols)
+	}
+}
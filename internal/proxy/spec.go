@@ -0,0 +1,320 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// addrPattern finds the address of a proxy in a line: a dotted IPv4 address, a bracketed
+// IPv6 address or a DNS hostname, a port and optional tags.
+var addrPattern = regexp.MustCompile(`(\d{1,3}(?:\.\d{1,3}){3}|\[[0-9A-Fa-f:.]+\]|` +
+	`(?i:(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z][a-z0-9-]*|localhost)):(\d{1,5})\b(?:#(\S*))?`)
+
+// addrSeparators may precede an address within a field, e.g. proxy=1.2.3.4:8080 or
+// <td>1.2.3.4:8080</td>. Any other character makes the address a part of a longer word.
+const addrSeparators = `=,;|"'(<>[{`
+
+var schemePattern = regexp.MustCompile(`^(?i:[a-z][a-z0-9]*)$`)
+
+// protocols are the schemes the checker can probe, a spec with one of them is only checked
+// over that protocol.
+var protocols = []string{"http", "https", "connect", "socks4", "socks4a", "socks5"}
+
+type Spec struct {
+	Scheme   string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// Tags are free-form labels carried from the input to the output, e.g. the country of
+	// the proxy, they are written as a URL fragment: 1.2.3.4:8080#country=US.
+	Tags map[string]string
+	// Source is the file or URL the proxy was read from.
+	Source string
+	Raw    string
+
+	err error
+}
+
+// ParseSpec parses a proxy line, an invalid line yields a spec holding Raw and the error.
+func ParseSpec(line string) (Spec, error) {
+	spec, err := parseSpec(line)
+	spec.err = err
+
+	return spec, err
+}
+
+func parseSpec(line string) (Spec, error) {
+	spec := Spec{Raw: line}
+
+	field := proxyField(line)
+	if field == "" {
+		return spec, fmt.Errorf("%w: %s", ErrInvalidProxy, line)
+	}
+
+	var (
+		host, port, fragment string
+		err                  error
+	)
+
+	if strings.Contains(field, "://") || strings.Contains(field, "@") {
+		spec.Scheme, spec.Username, spec.Password, host, port, fragment, err = parseURLField(field)
+		if err != nil {
+			return spec, fmt.Errorf("%w: %s: %w", ErrInvalidProxy, line, err)
+		}
+	} else {
+		loc := addrPattern.FindStringSubmatchIndex(field)
+		if loc[0] > 0 && !strings.ContainsRune(addrSeparators, rune(field[loc[0]-1])) {
+			// The address is a part of a longer word, e.g. foo_bar.example.com:80.
+			return spec, fmt.Errorf("%w: %s", ErrInvalidProxy, line)
+		}
+		host, port, fragment = field[loc[2]:loc[3]], field[loc[4]:loc[5]], ""
+		if loc[6] >= 0 {
+			fragment = field[loc[6]:loc[7]]
+		}
+
+		if after := field[loc[1]:]; strings.HasPrefix(after, ":") {
+			var creds string
+			creds, fragment, _ = strings.Cut(after[1:], "#")
+			if spec.Username, spec.Password, err = splitCredentials(creds); err != nil {
+				return spec, fmt.Errorf("%w: %s: %w", ErrInvalidProxy, line, err)
+			}
+		}
+	}
+
+	if strings.HasPrefix(host, "[") {
+		host = strings.Trim(host, "[]")
+		if ip := net.ParseIP(host); ip == nil || ip.To4() != nil {
+			return spec, fmt.Errorf("%w: invalid IPv6 address %s", ErrInvalidProxy, host)
+		}
+	} else if strings.Trim(host, "0123456789.") == "" && net.ParseIP(host) == nil {
+		return spec, fmt.Errorf("%w: invalid IPv4 address %s", ErrInvalidProxy, host)
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return spec, fmt.Errorf("%w: invalid port %s", ErrInvalidProxy, port)
+	}
+
+	tags, err := parseTags(fragment)
+	if err != nil {
+		return spec, fmt.Errorf("%w: invalid tags %s", ErrInvalidProxy, fragment)
+	}
+
+	spec.Host, spec.Port, spec.Tags = strings.ToLower(host), port, tags
+
+	return spec, nil
+}
+
+// proxyField returns the first whitespace separated field of line holding a proxy address.
+func proxyField(line string) string {
+	for _, field := range strings.Fields(line) {
+		if addrPattern.MatchString(field) {
+			return field
+		}
+	}
+
+	return ""
+}
+
+// parseURLField parses a proxy written as a URL, [scheme://][user[:password]@]host:port[#tags].
+// The credentials end at the last @, so they may hold any character and are unescaped like
+// those of a URL, a # in them has to be escaped as %23.
+func parseURLField(field string) (scheme, username, password, host, port, fragment string, err error) {
+	rest := field
+	if i := strings.Index(rest, "://"); i >= 0 {
+		if scheme = rest[:i]; !schemePattern.MatchString(scheme) {
+			return "", "", "", "", "", "", fmt.Errorf("invalid scheme %q", scheme)
+		}
+		scheme, rest = strings.ToLower(scheme), rest[i+3:]
+	}
+
+	rest, fragment, _ = strings.Cut(rest, "#")
+
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		user, pass, _ := strings.Cut(rest[:at], ":")
+		if username, err = url.PathUnescape(user); err != nil {
+			return "", "", "", "", "", "", fmt.Errorf("invalid username: %w", err)
+		}
+		if password, err = url.PathUnescape(pass); err != nil {
+			return "", "", "", "", "", "", fmt.Errorf("invalid password: %w", err)
+		}
+		rest = rest[at+1:]
+	}
+
+	loc := addrPattern.FindStringSubmatchIndex(rest)
+	if loc == nil || loc[0] != 0 {
+		return "", "", "", "", "", "", fmt.Errorf("invalid address %q", rest)
+	}
+
+	if after := rest[loc[1]:]; strings.HasPrefix(after, ":") {
+		if username != "" {
+			return "", "", "", "", "", "", fmt.Errorf("credentials given twice")
+		}
+		if username, password, err = splitCredentials(after[1:]); err != nil {
+			return "", "", "", "", "", "", err
+		}
+	}
+
+	return scheme, username, password, rest[loc[2]:loc[3]], rest[loc[4]:loc[5]], fragment, nil
+}
+
+// splitCredentials parses the user:password following the address in host:port:user:pass
+// lists, the password may hold colons. Dropping half of them would check another proxy.
+func splitCredentials(creds string) (username, password string, err error) {
+	username, password, _ = strings.Cut(creds, ":")
+	if username == "" || password == "" {
+		return "", "", fmt.Errorf("invalid credentials after the port")
+	}
+
+	return username, password, nil
+}
+
+func parseTags(fragment string) (map[string]string, error) {
+	if fragment == "" {
+		return nil, nil
+	}
+
+	values, err := url.ParseQuery(fragment)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(values))
+	for k, v := range values {
+		tags[k] = v[0]
+	}
+
+	return tags, nil
+}
+
+// Key identifies the proxy by its scheme and address, regardless of credentials, tags and
+// the spelling of the address.
+func (s Spec) Key() string {
+	host := s.Host
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+
+	port := s.Port
+	if n, err := strconv.Atoi(port); err == nil {
+		port = strconv.Itoa(n)
+	}
+
+	return s.Scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// Err returns the error the spec was parsed with.
+func (s Spec) Err() error {
+	return s.err
+}
+
+// Protocol returns the scheme of the spec if the checker can probe it.
+func (s Spec) Protocol() (string, bool) {
+	if slices.Contains(protocols, s.Scheme) {
+		return s.Scheme, true
+	}
+
+	return "", false
+}
+
+// isHostname reports whether the proxy is addressed by name rather than by IP.
+func (s Spec) isHostname() bool {
+	return net.ParseIP(s.Host) == nil
+}
+
+func (s Spec) Address() string {
+	return net.JoinHostPort(s.Host, s.Port)
+}
+
+func (s Spec) URL(scheme string) *url.URL {
+	u := &url.URL{Scheme: scheme, Host: s.Address()}
+
+	switch {
+	case s.Username != "" && s.Password != "":
+		u.User = url.UserPassword(s.Username, s.Password)
+	case s.Username != "":
+		u.User = url.User(s.Username)
+	}
+
+	return u
+}
+
+func (s Spec) String() string {
+	if s.Host == "" {
+		return s.Raw
+	}
+
+	return s.format(s.Password)
+}
+
+// Redacted is like String but masks the password, it is meant for logs and web pages.
+func (s Spec) Redacted() string {
+	if s.Host == "" {
+		return s.Raw
+	}
+
+	if s.Password == "" {
+		return s.format("")
+	}
+
+	return s.format("xxxxx")
+}
+
+func (s Spec) LogValue() slog.Value {
+	return slog.StringValue(s.Redacted())
+}
+
+func (s Spec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Spec) UnmarshalJSON(data []byte) error {
+	var line string
+	if err := json.Unmarshal(data, &line); err != nil {
+		return err
+	}
+
+	spec, err := ParseSpec(line)
+	if err != nil {
+		return err
+	}
+
+	*s = spec
+
+	return nil
+}
+
+func (s Spec) format(password string) string {
+	var b strings.Builder
+
+	if s.Scheme != "" {
+		b.WriteString(s.Scheme + "://")
+	}
+
+	switch {
+	case s.Username != "" && password != "":
+		b.WriteString(url.UserPassword(s.Username, password).String() + "@")
+	case s.Username != "":
+		b.WriteString(url.User(s.Username).String() + "@")
+	}
+
+	b.WriteString(s.Address())
+
+	if len(s.Tags) > 0 {
+		values := make(url.Values, len(s.Tags))
+		for k, v := range s.Tags {
+			values.Set(k, v)
+		}
+
+		b.WriteString("#" + values.Encode())
+	}
+
+	return b.String()
+}
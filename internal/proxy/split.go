@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var placeholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// SplitWriter routes every result to a file chosen by a pattern such as out/{protocol}.txt
+// or out/{country}/{anonymity}.txt. {protocol} stands for each protocol the proxy works
+// over, {anonymity} and {scheme} for those of the result and any other name for a tag of
+// the proxy. Files are created on their first result.
+type SplitWriter struct {
+	pattern string
+	opts    WriteOptions
+}
+
+func isSplitPattern(out string) bool {
+	return placeholder.MatchString(out)
+}
+
+func NewSplitWriter(pattern string, opts WriteOptions) *SplitWriter {
+	return &SplitWriter{pattern: pattern, opts: opts}
+}
+
+func (w *SplitWriter) Write(ctx context.Context, resultsCh <-chan Result) error {
+	pattern, err := expandPath(w.pattern)
+	if err != nil {
+		return err
+	}
+
+	enc := &splitEncoder{pattern: pattern, opts: w.opts, files: make(map[string]*outputFile)}
+
+	if err = encodeResults(ctx, enc, w.opts.keep, resultsCh); err != nil {
+		enc.Close()
+		return fmt.Errorf("failed to write split output: %w", err)
+	}
+
+	return nil
+}
+
+type splitEncoder struct {
+	pattern string
+	opts    WriteOptions
+	files   map[string]*outputFile
+}
+
+func (e *splitEncoder) Encode(res Result) error {
+	for _, filename := range e.filenames(res) {
+		f, ok := e.files[filename]
+		if !ok {
+			if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+				return err
+			}
+
+			opts := e.opts
+			if opts.Format == FormatAuto {
+				opts.Format = formatOf(filename)
+			}
+
+			var err error
+			if f, err = createFile(filename, opts); err != nil {
+				return err
+			}
+
+			e.files[filename] = f
+		}
+
+		if err := f.Encode(res); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close completes and closes every file, also when one of them fails.
+func (e *splitEncoder) Close() error {
+	var errs []error
+	for filename, f := range e.files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filename, err))
+		}
+		delete(e.files, filename)
+	}
+
+	return errors.Join(errs...)
+}
+
+// filenames expands the pattern for res, a proxy working over several protocols is written
+// to the file of every protocol when the pattern uses {protocol}. A failed proxy has no
+// protocol and goes to the unknown one.
+func (e *splitEncoder) filenames(res Result) []string {
+	protocols := []string{""}
+	if strings.Contains(e.pattern, "{protocol}") && len(res.Protocols) > 0 {
+		protocols = res.Protocols
+	}
+
+	filenames := make([]string, 0, len(protocols))
+	for _, protocol := range protocols {
+		filenames = append(filenames, placeholder.ReplaceAllStringFunc(e.pattern, func(m string) string {
+			var value string
+
+			switch name := m[1 : len(m)-1]; name {
+			case "protocol":
+				value = protocol
+			case "anonymity":
+				value = string(res.Anonymity)
+			case "scheme":
+				value = res.Proxy.Scheme
+			default:
+				value = res.Proxy.Tags[name]
+			}
+
+			return pathSegment(value)
+		}))
+	}
+
+	return filenames
+}
+
+// pathSegment keeps a value from escaping its place in the pattern.
+func pathSegment(value string) string {
+	value = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(value))
+
+	if value == "" || value == "." || value == ".." {
+		return "unknown"
+	}
+
+	return value
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitWriter_Write(t *testing.T) {
+	dir := t.TempDir()
+
+	resultsCh := make(chan Result, 4)
+	resultsCh <- Result{Proxy: specOf("1.1.1.1:80#country=US"), Protocols: []string{"http", "socks5"}, Anonymity: Elite}
+	resultsCh <- Result{Proxy: specOf("2.2.2.2:80#country=DE"), Protocols: []string{"http"}, Anonymity: Anonymous}
+	resultsCh <- Result{Proxy: specOf("3.3.3.3:80"), Protocols: []string{"socks4"}, Anonymity: Elite}
+	resultsCh <- Result{Proxy: specOf("4.4.4.4:80#country=US"), Category: CategoryTimeout}
+	close(resultsCh)
+
+	writer := NewWriter(filepath.Join(dir, "{country}", "{protocol}.txt"), WriteOptions{})
+	if err := writer.Write(context.Background(), resultsCh); err != nil {
+		t.Fatalf("failed to write proxies: %v", err)
+	}
+
+	expected := map[string]string{
+		"US/http.txt":        "1.1.1.1:80#country=US\n",
+		"US/socks5.txt":      "1.1.1.1:80#country=US\n",
+		"DE/http.txt":        "2.2.2.2:80#country=DE\n",
+		"unknown/socks4.txt": "3.3.3.3:80\n",
+	}
+
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+			continue
+		}
+
+		if string(data) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, data)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "DE", "socks5.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected no file without proxies, got %v", err)
+	}
+}
+
+func TestSplitWriter_Failed(t *testing.T) {
+	dir := t.TempDir()
+
+	resultsCh := make(chan Result, 2)
+	resultsCh <- Result{Proxy: specOf("1.1.1.1:80"), Protocols: []string{"http"}}
+	resultsCh <- Result{Proxy: specOf("4.4.4.4:80"), Error: "request failed", Category: CategoryTimeout}
+	close(resultsCh)
+
+	writer := NewWriter(filepath.Join(dir, "{protocol}.txt"), WriteOptions{Failed: true})
+	if err := writer.Write(context.Background(), resultsCh); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "unknown.txt"))
+	if err != nil {
+		t.Fatalf("expected failures to be written without a protocol: %v", err)
+	}
+
+	if string(data) != "4.4.4.4:80\ttimeout\trequest failed\n" {
+		t.Errorf("unexpected report %q", data)
+	}
+}
+
+func TestSplitWriter_Format(t *testing.T) {
+	dir := t.TempDir()
+
+	resultsCh := make(chan Result, 2)
+	resultsCh <- Result{Proxy: specOf("1.1.1.1:80"), Protocols: []string{"http"}, Anonymity: Elite}
+	resultsCh <- Result{Proxy: specOf("2.2.2.2:80"), Protocols: []string{"http"}, Anonymity: Elite}
+	close(resultsCh)
+
+	writer := NewWriter(filepath.Join(dir, "{anonymity}.json"), WriteOptions{})
+	if err := writer.Write(context.Background(), resultsCh); err != nil {
+		t.Fatalf("failed to write proxies: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "elite.json"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+
+	if data[0] != '[' || data[len(data)-2] != ']' {
+		t.Errorf("expected a complete JSON array, got %s", data)
+	}
+}
+
+func TestPathSegment(t *testing.T) {
+	for value, expected := range map[string]string{
+		"US":        "US",
+		"":          "unknown",
+		"..":        "unknown",
+		"../../etc": ".._.._etc",
+	} {
+		if s := pathSegment(value); s != expected {
+			t.Errorf("pathSegment(%q) = %q, expected %q", value, s, expected)
+		}
+	}
+}
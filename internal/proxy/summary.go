@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Summary counts the checked proxies, Protocols counts the working proxies by the protocols
+// they work over and Errors the failed ones by error category.
+type Summary struct {
+	Total      int              `json:"total"`
+	Alive      int              `json:"alive"`
+	Duplicates int              `json:"duplicates,omitempty"`
+	Protocols  map[string]int   `json:"protocols,omitempty"`
+	Errors     map[Category]int `json:"errors,omitempty"`
+}
+
+func Summarize(results []Result) Summary {
+	var s Summary
+	for _, res := range results {
+		s.Add(res)
+	}
+
+	return s
+}
+
+func (s *Summary) Add(res Result) {
+	s.Total++
+
+	if res.OK() {
+		s.Alive++
+
+		if s.Protocols == nil {
+			s.Protocols = make(map[string]int)
+		}
+		for _, p := range res.Protocols {
+			s.Protocols[p]++
+		}
+
+		return
+	}
+
+	if s.Errors == nil {
+		s.Errors = make(map[Category]int)
+	}
+	s.Errors[res.Category]++
+}
+
+func (s Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "checked %d, alive %d", s.Total, s.Alive)
+
+	if s.Duplicates > 0 {
+		fmt.Fprintf(&b, ", duplicates %d", s.Duplicates)
+	}
+
+	s.eachError(func(c Category, n int) {
+		fmt.Fprintf(&b, ", %s %d", c, n)
+	})
+
+	return b.String()
+}
+
+// Report lays the summary out as a table with a line per protocol and error category.
+func (s Summary) Report() string {
+	var b strings.Builder
+
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "checked\t%d\n", s.Total)
+	fmt.Fprintf(w, "alive\t%d\n", s.Alive)
+
+	if s.Duplicates > 0 {
+		fmt.Fprintf(w, "duplicates\t%d\n", s.Duplicates)
+	}
+
+	s.eachProtocol(func(p string, n int) {
+		fmt.Fprintf(w, "  %s\t%d\n", p, n)
+	})
+
+	if len(s.Errors) > 0 {
+		fmt.Fprintf(w, "failed\t%d\n", s.Total-s.Alive)
+	}
+
+	s.eachError(func(c Category, n int) {
+		fmt.Fprintf(w, "  %s\t%d\n", c, n)
+	})
+
+	w.Flush()
+
+	return b.String()
+}
+
+func (s Summary) LogValue() slog.Value {
+	protos := make([]any, 0, len(s.Protocols))
+	s.eachProtocol(func(p string, n int) {
+		protos = append(protos, slog.Int(p, n))
+	})
+
+	errs := make([]any, 0, len(s.Errors))
+	s.eachError(func(c Category, n int) {
+		errs = append(errs, slog.Int(string(c), n))
+	})
+
+	return slog.GroupValue(
+		slog.Int("total", s.Total),
+		slog.Int("alive", s.Alive),
+		slog.Int("duplicates", s.Duplicates),
+		slog.Group("protocols", protos...),
+		slog.Group("errors", errs...),
+	)
+}
+
+// eachProtocol visits the protocol counts, known protocols first.
+func (s Summary) eachProtocol(fn func(string, int)) {
+	names := make([]string, 0, len(s.Protocols))
+	for p := range s.Protocols {
+		if !slices.Contains(protocols, p) {
+			names = append(names, p)
+		}
+	}
+	slices.Sort(names)
+
+	for _, p := range append(slices.Clone(protocols), names...) {
+		if n := s.Protocols[p]; n > 0 {
+			fn(p, n)
+		}
+	}
+}
+
+// eachError visits the error counts in the order of categories.
+func (s Summary) eachError(fn func(Category, int)) {
+	for _, c := range categories {
+		if n := s.Errors[c.category]; n > 0 {
+			fn(c.category, n)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]Result{
+		{Protocols: []string{"http"}},
+		{Category: CategoryTimeout},
+		{Category: CategoryRefused},
+		{Category: CategoryTimeout},
+	})
+
+	if s.Total != 4 || s.Alive != 1 {
+		t.Fatalf("expected 4 checked and 1 alive, got %+v", s)
+	}
+
+	if s.Errors[CategoryTimeout] != 2 || s.Errors[CategoryRefused] != 1 {
+		t.Fatalf("unexpected error counts %v", s.Errors)
+	}
+
+	expected := "checked 4, alive 1, timeout 2, refused 1"
+	if s.String() != expected {
+		t.Errorf("expected %q, got %q", expected, s.String())
+	}
+}
+
+func TestSummary_Duplicates(t *testing.T) {
+	s := Summarize([]Result{{Protocols: []string{"http"}}})
+	s.Duplicates = 3
+
+	expected := "checked 1, alive 1, duplicates 3"
+	if s.String() != expected {
+		t.Errorf("expected %q, got %q", expected, s.String())
+	}
+}
+
+func TestSummary_Report(t *testing.T) {
+	s := Summarize([]Result{
+		{Protocols: []string{"socks5"}},
+		{Protocols: []string{"http", "socks5"}},
+		{Category: CategoryTimeout},
+	})
+
+	if s.Protocols["socks5"] != 2 || s.Protocols["http"] != 1 {
+		t.Fatalf("unexpected protocol counts %v", s.Protocols)
+	}
+
+	expected := "checked    3\n" +
+		"alive      2\n" +
+		"  http     1\n" +
+		"  socks5   2\n" +
+		"failed     1\n" +
+		"  timeout  1\n"
+	if s.Report() != expected {
+		t.Errorf("expected %q, got %q", expected, s.Report())
+	}
+}
@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// templates holds the built-in template formats, a format is added by dropping a
+// <format>.tmpl file into the directory. A template is executed once with all working
+// results.
+//
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+const templatePrefix = "template:"
+
+var templateFuncs = template.FuncMap{
+	"url":         proxyURL,
+	"pac":         pacDirective,
+	"proxychains": proxychainsType,
+}
+
+// isTemplate reports whether f is a built-in template format or a template:path format.
+func (f Format) isTemplate() bool {
+	if strings.HasPrefix(string(f), templatePrefix) {
+		return true
+	}
+
+	_, err := templates.Open("templates/" + string(f) + ".tmpl")
+	return f != FormatAuto && err == nil
+}
+
+func loadTemplate(f Format) (*template.Template, error) {
+	var (
+		name = string(f)
+		text []byte
+		err  error
+	)
+
+	if path, ok := strings.CutPrefix(name, templatePrefix); ok {
+		if path, err = expandPath(path); err == nil {
+			text, err = os.ReadFile(path)
+		}
+	} else {
+		text, err = templates.ReadFile("templates/" + name + ".tmpl")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template %s: %w", name, err)
+	}
+
+	return template.New(name).Funcs(templateFuncs).Parse(string(text))
+}
+
+// templateEncoder collects the results and renders them all at once on Close.
+type templateEncoder struct {
+	w       io.Writer
+	tmpl    *template.Template
+	results []Result
+}
+
+func (e *templateEncoder) Encode(res Result) error {
+	e.results = append(e.results, res)
+	return nil
+}
+
+func (e *templateEncoder) Close() error {
+	return e.tmpl.Execute(e.w, e.results)
+}
+
+// clientScheme is the scheme clients use for the fastest protocol of a proxy, CONNECT
+// tunnelling goes through a plain HTTP proxy.
+func clientScheme(res Result) string {
+	switch protocol := res.FastestProtocol(); protocol {
+	case "connect", "":
+		return "http"
+	default:
+		return protocol
+	}
+}
+
+// proxyURL renders the proxy the way curl and python requests expect it, with credentials.
+func proxyURL(res Result) string {
+	return res.Proxy.URL(clientScheme(res)).String()
+}
+
+func pacDirective(res Result) string {
+	switch clientScheme(res) {
+	case "socks5":
+		return "SOCKS5 " + res.Proxy.Address()
+	case "socks4", "socks4a":
+		return "SOCKS " + res.Proxy.Address()
+	case "https":
+		return "HTTPS " + res.Proxy.Address()
+	default:
+		return "PROXY " + res.Proxy.Address()
+	}
+}
+
+// proxychainsType returns the proxychains type of a proxy, none for an HTTPS proxy since
+// proxychains cannot talk TLS to a proxy.
+func proxychainsType(res Result) string {
+	switch scheme := clientScheme(res); scheme {
+	case "socks4a":
+		return "socks4"
+	case "https":
+		return ""
+	default:
+		return scheme
+	}
+}
@@ -4,90 +4,188 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 )
 
+// Writer writes the working proxies, or only the failed ones for a failure report.
 type Writer interface {
-	Write(ctx context.Context, proxiesCh <-chan string) error
+	Write(ctx context.Context, resultsCh <-chan Result) error
+}
+
+// WriteOptions select the output format, FormatAuto picks the format of a file by its
+// extension and writes text to stdout. Columns apply to the CSV format. Failed writes a
+// report of the failed proxies with their input line, error category and error instead.
+type WriteOptions struct {
+	Format  Format
+	Columns []string
+	Failed  bool
+}
+
+// keep reports whether res belongs in the output.
+func (o WriteOptions) keep(res Result) bool {
+	return res.OK() != o.Failed
 }
 
 type FileWriter struct {
 	filename string
+	opts     WriteOptions
 }
 
-type StdoutWriter struct{}
+type StdoutWriter struct {
+	opts WriteOptions
+}
 
-func NewWriter(out string) Writer {
-	if out == "stdout" {
-		return NewStdoutWriter()
+// NewWriter writes to stdout, to a file or, when out holds placeholders such as
+// out/{protocol}.txt, to one file per distinct value.
+func NewWriter(out string, opts WriteOptions) Writer {
+	switch {
+	case out == "stdout":
+		return NewStdoutWriter(opts)
+	case isSplitPattern(out):
+		return NewSplitWriter(out, opts)
+	default:
+		return NewFileWriter(out, opts)
 	}
-
-	return NewFileWriter(out)
 }
 
-func NewStdoutWriter() Writer {
-	return &StdoutWriter{}
+func NewStdoutWriter(opts WriteOptions) Writer {
+	return &StdoutWriter{opts: opts}
 }
 
-func NewFileWriter(filename string) *FileWriter {
-	return &FileWriter{filename: filename}
+func NewFileWriter(filename string, opts WriteOptions) *FileWriter {
+	if opts.Format == FormatAuto {
+		opts.Format = formatOf(filename)
+	}
+
+	return &FileWriter{filename: filename, opts: opts}
 }
 
-func (w *FileWriter) Write(ctx context.Context, proxiesCh <-chan string) error {
+func (w *FileWriter) Write(ctx context.Context, resultsCh <-chan Result) error {
 	filename, err := expandPath(w.filename)
 	if err != nil {
 		return err
 	}
 
-	file, err := os.Create(filename)
+	f, err := createFile(filename, w.opts)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	writer := bufio.NewWriter(file)
+	// encodeResults closes f once all results are written.
+	if err = encodeResults(ctx, f, w.opts.keep, resultsCh); err != nil {
+		f.file.Close()
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			for proxy := range proxiesCh {
-				if _, err = writer.WriteString(proxy + "\n"); err != nil {
-					return fmt.Errorf("failed to write remaining data to file: %w", err)
-				}
-			}
+	return nil
+}
 
-			return writer.Flush()
-		case proxy, ok := <-proxiesCh:
-			if !ok {
-				return writer.Flush()
-			}
+func (w *StdoutWriter) Write(ctx context.Context, resultsCh <-chan Result) error {
+	enc, err := newEncoder(os.Stdout, w.opts)
+	if err != nil {
+		return err
+	}
 
-			if _, err = writer.WriteString(proxy + "\n"); err != nil {
-				return fmt.Errorf("failed to write to file: %w", err)
-			}
-		}
+	if err = encodeResults(ctx, enc, w.opts.keep, resultsCh); err != nil {
+		return err
 	}
+
+	return ctx.Err()
 }
 
-func (w *StdoutWriter) Write(ctx context.Context, proxiesCh <-chan string) error {
+// encodeResults encodes the results to keep until resultsCh is closed and closes enc, results
+// still arriving after ctx is cancelled are written as well.
+func encodeResults(ctx context.Context, enc encoder, keep func(Result) bool, resultsCh <-chan Result) error {
+	encode := func(res Result) error {
+		if !keep(res) {
+			return nil
+		}
+
+		return enc.Encode(res)
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			for proxy := range proxiesCh {
-				if _, err := fmt.Fprintln(os.Stdout, proxy); err != nil {
+			for res := range resultsCh {
+				if err := encode(res); err != nil {
 					return err
 				}
 			}
 
-			return ctx.Err()
-		case proxy, ok := <-proxiesCh:
+			return enc.Close()
+		case res, ok := <-resultsCh:
 			if !ok {
-				return nil
+				return enc.Close()
 			}
 
-			if _, err := fmt.Fprintln(os.Stdout, proxy); err != nil {
+			if err := encode(res); err != nil {
 				return err
 			}
 		}
 	}
 }
+
+// outputFile encodes results into a possibly compressed file, Close completes the document
+// and closes the file.
+type outputFile struct {
+	file *os.File
+	out  io.WriteCloser
+	buf  *bufio.Writer
+	enc  encoder
+}
+
+func createFile(filename string, opts WriteOptions) (*outputFile, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	f, err := newOutputFile(file, opts)
+	if err != nil {
+		file.Close()
+		os.Remove(filename)
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func newOutputFile(file *os.File, opts WriteOptions) (*outputFile, error) {
+	out, err := compress(file.Name(), file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	buf := bufio.NewWriter(out)
+
+	enc, err := newEncoder(buf, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &outputFile{file: file, out: out, buf: buf, enc: enc}, nil
+}
+
+func (f *outputFile) Encode(res Result) error {
+	return f.enc.Encode(res)
+}
+
+func (f *outputFile) Close() error {
+	err := f.enc.Close()
+
+	if flushErr := f.buf.Flush(); err == nil {
+		err = flushErr
+	}
+
+	if closeErr := f.out.Close(); err == nil {
+		err = closeErr
+	}
+
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
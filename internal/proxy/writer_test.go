@@ -5,6 +5,7 @@ import (
 	"context"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -14,19 +15,21 @@ func TestFileWriter_Write(t *testing.T) {
 	filename := "testdata/write_proxies.txt"
 	proxies := []string{"127.0.0.1:8080", "192.168.0.1:3128"}
 
-	writer := NewFileWriter(filename)
-	proxiesCh := make(chan string, len(proxies))
+	writer := NewFileWriter(filename, WriteOptions{})
+	resultsCh := make(chan Result, len(proxies)+1)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
 	go func() {
-		defer close(proxiesCh)
+		defer close(resultsCh)
 		for _, proxy := range proxies {
-			proxiesCh <- proxy
+			spec, _ := ParseSpec(proxy)
+			resultsCh <- Result{Proxy: spec, Protocols: []string{"http"}}
 		}
+		resultsCh <- Result{Proxy: Spec{Host: "10.0.0.1", Port: "80"}, Error: "request failed", Category: CategoryTimeout}
 	}()
 
-	err := writer.Write(ctx, proxiesCh)
+	err := writer.Write(ctx, resultsCh)
 	if err != nil {
 		t.Fatalf("failed to write proxies to file: %v", err)
 	}
@@ -51,16 +54,18 @@ func TestFileWriter_Write(t *testing.T) {
 func TestStdoutWriter_Write(t *testing.T) {
 	proxies := []string{"127.0.0.1:8080", "192.168.0.1:3128"}
 
-	writer := NewStdoutWriter()
-	proxiesCh := make(chan string, len(proxies))
+	writer := NewStdoutWriter(WriteOptions{})
+	resultsCh := make(chan Result, len(proxies)+1)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
 	go func() {
-		defer close(proxiesCh)
+		defer close(resultsCh)
 		for _, proxy := range proxies {
-			proxiesCh <- proxy
+			spec, _ := ParseSpec(proxy)
+			resultsCh <- Result{Proxy: spec, Protocols: []string{"http"}}
 		}
+		resultsCh <- Result{Proxy: Spec{Host: "10.0.0.1", Port: "80"}, Error: "request failed", Category: CategoryTimeout}
 	}()
 
 	r, w, err := os.Pipe()
@@ -76,7 +81,7 @@ func TestStdoutWriter_Write(t *testing.T) {
 
 	writeErrCh := make(chan error)
 	go func() {
-		writeErrCh <- writer.Write(ctx, proxiesCh)
+		writeErrCh <- writer.Write(ctx, resultsCh)
 	}()
 
 	var buf bytes.Buffer
@@ -109,3 +114,71 @@ func TestStdoutWriter_Write(t *testing.T) {
 		}
 	}
 }
+
+func TestFileWriter_Format(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "proxies.csv")
+
+	resultsCh := make(chan Result, 2)
+	resultsCh <- Result{Proxy: specOf("127.0.0.1:8080#country=DE"), Protocols: []string{"http"}}
+	resultsCh <- Result{Proxy: specOf("10.0.0.1:80"), Error: "request failed", Category: CategoryTimeout}
+	close(resultsCh)
+
+	if err := NewFileWriter(filename, WriteOptions{Columns: []string{"proxy", "country"}}).Write(context.Background(), resultsCh); err != nil {
+		t.Fatalf("failed to write proxies: %v", err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+
+	expected := "proxy,country\n127.0.0.1:8080#country=DE,DE\n"
+	if string(content) != expected {
+		t.Errorf("expected %q, got %q", expected, content)
+	}
+}
+
+func TestFileWriter_Failed(t *testing.T) {
+	invalid, _ := ParseSpec("not a proxy")
+	invalid.Source = "list.txt"
+
+	results := []Result{
+		{Proxy: specOf("127.0.0.1:8080"), Protocols: []string{"http"}},
+		{Proxy: invalid, Source: invalid.Source, Error: "invalid proxy: not a proxy", Category: CategoryInvalid},
+		{Proxy: specOf("10.0.0.1:80"), Error: "tcp4: refused\ntcp6:\tunreachable", Category: CategoryTimeout},
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"failed.txt", "not a proxy\tinvalid\tinvalid proxy: not a proxy\n10.0.0.1:80\ttimeout\ttcp4: refused; tcp6: unreachable\n"},
+		{"failed.jsonl", `{"input":"not a proxy","source":"list.txt","category":"invalid","error":"invalid proxy: not a proxy","checked_at":"0001-01-01T00:00:00Z"}` + "\n" +
+			`{"input":"10.0.0.1:80","category":"timeout","error":"tcp4: refused\ntcp6:\tunreachable","checked_at":"0001-01-01T00:00:00Z"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), tt.name)
+
+			resultsCh := make(chan Result, len(results))
+			for _, res := range results {
+				resultsCh <- res
+			}
+			close(resultsCh)
+
+			if err := NewFileWriter(filename, WriteOptions{Failed: true}).Write(context.Background(), resultsCh); err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("failed to read report: %v", err)
+			}
+
+			if string(content) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, content)
+			}
+		})
+	}
+}
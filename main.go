@@ -20,6 +20,7 @@ func main() {
 		cmd.NewCliCommand(),
 		cmd.NewServerCommand(),
 		cmd.NewBotCommand(),
+		cmd.NewJudgeCommand(),
 	}
 
 	if err := root(os.Args[1:], cmds); err != nil {
@@ -28,7 +29,7 @@ func main() {
 		}
 
 		fmt.Printf("failed, %v\n", err.Error())
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
 